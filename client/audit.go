@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"google.golang.org/grpc"
+)
+
+var errAuditLogBroken = errors.New("audit log verification failed")
+
+func runVerifyAuditLog(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	fs := flag.NewFlagSet("verify-audit-log", flag.ExitOnError)
+	email, password := credentialFlags(fs)
+	fs.Parse(args)
+
+	ctx, err := login(ctx, conn, *email, *password)
+	if err != nil {
+		return err
+	}
+
+	res, err := protos.NewAuditServiceClient(conn).VerifyAuditLog(ctx, &protos.VerifyAuditLogRequest{})
+	if err != nil {
+		return fmt.Errorf("VerifyAuditLog failed: %w", err)
+	}
+
+	if !res.Valid {
+		fmt.Printf("Broken link at event %d after %d valid events: %s\n", res.FirstBrokenId, res.CheckedEvents, res.Reason)
+		return errAuditLogBroken
+	}
+
+	fmt.Printf("Verified %d events, head hash %s\n", res.CheckedEvents, res.HeadHash)
+	return nil
+}
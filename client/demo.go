@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func runDemo(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	adminEmail, adminPassword := credentialFlags(fs)
+	fs.Parse(args)
+
+	if *adminEmail == "" {
+		return errors.New("demo needs -email and -password of an admin")
+	}
+
+	// Every call requires a bearer token, and only admins may purge users.
+	ctx, err := login(ctx, conn, *adminEmail, *adminPassword)
+	if err != nil {
+		return err
+	}
+
+	client := protos.NewUserServiceClient(conn)
+
+	// 1. Create User
+	createResp, err := client.CreateUser(ctx, &protos.CreateUserRequest{
+		Name:     "Alice",
+		Email:    "alice@example.com",
+		Password: "secret",
+	})
+	if err != nil {
+		return fmt.Errorf("CreateUser failed: %w", err)
+	}
+	fmt.Println("Created:", createResp.User)
+
+	// 2. Get User
+	getResp, err := client.GetUser(ctx, &protos.GetUserRequest{Id: createResp.User.Id})
+	if err != nil {
+		return fmt.Errorf("GetUser failed: %w", err)
+	}
+	fmt.Println("Fetched:", getResp.User)
+
+	// 3. Update User
+	email := "alice.new@example.com"
+	updateResp, err := client.UpdateUser(ctx, &protos.UpdateUserRequest{
+		Id:         createResp.User.Id,
+		Name:       "Alice Updated",
+		Email:      &email,
+		Version:    getResp.User.Version,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name", "email"}},
+	})
+	if err != nil {
+		return fmt.Errorf("UpdateUser failed: %w", err)
+	}
+	fmt.Println("Updated:", updateResp.User)
+
+	// 4. List Users
+	listResp, err := client.AllUsers(ctx, &emptypb.Empty{})
+	if err != nil {
+		return fmt.Errorf("AllUsers failed: %w", err)
+	}
+	fmt.Println("List Users:", listResp.Users)
+
+	// 5. Purge User. A soft delete would keep the email reserved and stop
+	// the demo from running again.
+	purgeResp, err := client.PurgeUser(ctx, &protos.PurgeUserRequest{Id: createResp.User.Id})
+	if err != nil {
+		return fmt.Errorf("PurgeUser failed: %w", err)
+	}
+	fmt.Println("Purged:", purgeResp.Success)
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func runHealth(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	fs := flag.NewFlagSet("health", flag.ExitOnError)
+	service := fs.String("service", "", "service to check, such as protos.UserService (default the whole server)")
+	fs.Parse(args)
+
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: *service})
+	if err != nil {
+		return fmt.Errorf("Health check failed: %w", err)
+	}
+
+	fmt.Println(res.Status)
+	if res.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("server is %s", res.Status)
+	}
+	return nil
+}
@@ -2,71 +2,122 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"sort"
 	"time"
 
+	"github.com/cndrsdrmn/go-grpc/internal/tlsconfig"
 	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/grpc/metadata"
 )
 
-func main() {
-	conn, err := grpc.NewClient(
-		"localhost:50051",
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
-	if err != nil {
-		log.Fatalf("Cannot connect to: %v", err)
-	}
-	defer conn.Close()
-
-	client := protos.NewUserServiceClient(conn)
+type command struct {
+	usage string
+	run   func(ctx context.Context, conn *grpc.ClientConn, args []string) error
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+var commands = map[string]command{
+	"demo":             {usage: "create, update, list and purge a demo user as an admin", run: runDemo},
+	"health":           {usage: "check whether the server is serving; fails otherwise", run: runHealth},
+	"list":             {usage: "list services, or the methods of one service, through reflection", run: runList},
+	"describe":         {usage: "print the schema of services, methods, messages or enums through reflection", run: runDescribe},
+	"verify-audit-log": {usage: "verify the audit log hash chain (admin)", run: runVerifyAuditLog},
+}
 
-	// 1. Create User
-	createResp, err := client.CreateUser(ctx, &protos.CreateUserRequest{
-		Name:  "Alice",
-		Email: "alice@example.com",
-	})
-	if err != nil {
-		log.Fatalf("CreateUser failed: %v", err)
+func transportCredentials(useTLS bool, opts tlsconfig.ClientOptions) (credentials.TransportCredentials, error) {
+	if !useTLS {
+		return insecure.NewCredentials(), nil
 	}
-	fmt.Println("Created:", createResp.User)
 
-	// 2. Get User
-	getResp, err := client.GetUser(ctx, &protos.GetUserRequest{Id: createResp.User.Id})
+	cfg, err := tlsconfig.ClientConfig(opts)
 	if err != nil {
-		log.Fatalf("GetUser failed: %v", err)
+		return nil, err
 	}
-	fmt.Println("Fetched:", getResp.User)
-
-	// 3. Update User
-	email := "alice.new@example.com"
-	updateResp, err := client.UpdateUser(ctx, &protos.UpdateUserRequest{
-		Id:    createResp.User.Id,
-		Name:  "Alice Updated",
-		Email: &email,
+	return credentials.NewTLS(cfg), nil
+}
+
+func login(ctx context.Context, conn *grpc.ClientConn, email, password string) (context.Context, error) {
+	res, err := protos.NewAuthServiceClient(conn).Login(ctx, &protos.LoginRequest{
+		Email:    email,
+		Password: password,
 	})
 	if err != nil {
-		log.Fatalf("UpdateUser failed: %v", err)
+		return nil, fmt.Errorf("Login failed: %w", err)
 	}
-	fmt.Println("Updated:", updateResp.User)
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+res.AccessToken), nil
+}
+
+// credentialFlags adds the -email and -password flags used by commands that
+// log in before calling the server.
+func credentialFlags(fs *flag.FlagSet) (*string, *string) {
+	email := fs.String("email", "", "email to log in with")
+	password := fs.String("password", os.Getenv("CLIENT_PASSWORD"), "password to log in with (default $CLIENT_PASSWORD)")
+	return email, password
+}
 
-	// 4. List Users
-	listResp, err := client.AllUsers(ctx, &emptypb.Empty{})
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [command] [command flags]\n\nCommands:\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %-18s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(flag.CommandLine.Output(), "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:50051", "server address")
+	timeout := flag.Duration("timeout", 5*time.Second, "deadline for the whole command")
+	useTLS := flag.Bool("tls", false, "connect over TLS")
+	var tlsOpts tlsconfig.ClientOptions
+	flag.StringVar(&tlsOpts.CAFile, "tls-ca", "", "CA bundle used to verify the server instead of the system roots")
+	flag.StringVar(&tlsOpts.CertFile, "tls-cert", "", "client certificate for mutual TLS")
+	flag.StringVar(&tlsOpts.KeyFile, "tls-key", "", "client private key for mutual TLS")
+	flag.StringVar(&tlsOpts.ServerName, "tls-server-name", "", "override the server name used for verification")
+	flag.Usage = usage
+	flag.Parse()
+
+	name := "demo"
+	if flag.NArg() > 0 {
+		name = flag.Arg(0)
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(flag.CommandLine.Output(), "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	creds, err := transportCredentials(*useTLS, tlsOpts)
 	if err != nil {
-		log.Fatalf("ListUsers failed: %v", err)
+		log.Fatalf("Cannot load TLS configuration: %v", err)
 	}
-	fmt.Println("List Users:", listResp.Users)
 
-	// 5. Delete User
-	delResp, err := client.DeleteUser(ctx, &protos.DeleteUserRequest{Id: createResp.User.Id})
+	conn, err := grpc.NewClient(*addr, grpc.WithTransportCredentials(creds))
 	if err != nil {
-		log.Fatalf("DeleteUser failed: %v", err)
+		log.Fatalf("Cannot connect to: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var args []string
+	if flag.NArg() > 1 {
+		args = flag.Args()[1:]
+	}
+
+	if err := cmd.run(ctx, conn, args); err != nil {
+		log.Fatal(err)
 	}
-	fmt.Println("Deleted:", delResp.Success)
 }
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// reflectionClient resolves symbols through the server's reflection service,
+// fetching each file descriptor and its dependencies once per stream.
+type reflectionClient struct {
+	stream  reflectionpb.ServerReflection_ServerReflectionInfoClient
+	files   *protoregistry.Files
+	fetched map[string]*descriptorpb.FileDescriptorProto
+}
+
+func newReflectionClient(ctx context.Context, conn *grpc.ClientConn) (*reflectionClient, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &reflectionClient{
+		stream:  stream,
+		files:   new(protoregistry.Files),
+		fetched: map[string]*descriptorpb.FileDescriptorProto{},
+	}, nil
+}
+
+func (c *reflectionClient) Close() error {
+	return c.stream.CloseSend()
+}
+
+func (c *reflectionClient) request(req *reflectionpb.ServerReflectionRequest) (*reflectionpb.ServerReflectionResponse, error) {
+	if err := c.stream.Send(req); err != nil {
+		return nil, err
+	}
+	res, err := c.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if e := res.GetErrorResponse(); e != nil {
+		return nil, status.Error(codes.Code(e.ErrorCode), e.ErrorMessage)
+	}
+	return res, nil
+}
+
+func (c *reflectionClient) ListServices() ([]string, error) {
+	res, err := c.request(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, service := range res.GetListServicesResponse().GetService() {
+		names = append(names, service.Name)
+	}
+	return names, nil
+}
+
+// Resolve returns the descriptor of a fully-qualified service, method,
+// message, enum or field name.
+func (c *reflectionClient) Resolve(symbol string) (protoreflect.Descriptor, error) {
+	name := protoreflect.FullName(strings.TrimPrefix(symbol, "."))
+	if d, err := c.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+
+	// Not every server indexes methods and fields, so fall back to the
+	// enclosing symbol.
+	res, err := c.request(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: string(name)},
+	})
+	if status.Code(err) == codes.NotFound && name.Parent() != "" {
+		res, err = c.request(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: string(name.Parent())},
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %s: %w", name, err)
+	}
+
+	if err := c.register(res.GetFileDescriptorResponse().GetFileDescriptorProto()); err != nil {
+		return nil, err
+	}
+	d, err := c.files.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %s: %w", name, err)
+	}
+	return d, nil
+}
+
+func (c *reflectionClient) register(raw [][]byte) error {
+	var names []string
+	for _, b := range raw {
+		fd := new(descriptorpb.FileDescriptorProto)
+		if err := proto.Unmarshal(b, fd); err != nil {
+			return fmt.Errorf("invalid file descriptor: %w", err)
+		}
+		c.fetched[fd.GetName()] = fd
+		names = append(names, fd.GetName())
+	}
+
+	for _, name := range names {
+		if err := c.load(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load builds a fetched file after its dependencies, asking the server for
+// any dependency it did not send along.
+func (c *reflectionClient) load(path string) error {
+	if _, err := c.files.FindFileByPath(path); err == nil {
+		return nil
+	}
+
+	fd, ok := c.fetched[path]
+	if !ok {
+		res, err := c.request(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: path},
+		})
+		if err != nil {
+			return fmt.Errorf("cannot fetch %s: %w", path, err)
+		}
+		return c.register(res.GetFileDescriptorResponse().GetFileDescriptorProto())
+	}
+
+	for _, dep := range fd.GetDependency() {
+		if err := c.load(dep); err != nil {
+			return err
+		}
+	}
+
+	file, err := protodesc.NewFile(fd, c.files)
+	if err != nil {
+		return fmt.Errorf("invalid file descriptor %s: %w", path, err)
+	}
+	return c.files.RegisterFile(file)
+}
+
+// reflectionCommand parses the shared flags of the reflection commands and
+// opens a reflection stream, logging in first when -email is given.
+func reflectionCommand(ctx context.Context, conn *grpc.ClientConn, name string, args []string) (*reflectionClient, []string, error) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	email, password := credentialFlags(fs)
+	fs.Parse(args)
+
+	if *email != "" {
+		var err error
+		if ctx, err = login(ctx, conn, *email, *password); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	client, err := newReflectionClient(ctx, conn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Reflection failed: %w", err)
+	}
+	return client, fs.Args(), nil
+}
+
+func runList(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	client, args, err := reflectionCommand(ctx, conn, "list", args)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if len(args) == 0 {
+		services, err := client.ListServices()
+		if err != nil {
+			return fmt.Errorf("Reflection failed: %w", err)
+		}
+		for _, name := range services {
+			fmt.Println(name)
+		}
+		return nil
+	}
+
+	d, err := client.Resolve(args[0])
+	if err != nil {
+		return err
+	}
+	service, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return fmt.Errorf("%s is not a service", d.FullName())
+	}
+	for i := 0; i < service.Methods().Len(); i++ {
+		fmt.Println(service.Methods().Get(i).FullName())
+	}
+	return nil
+}
+
+func runDescribe(ctx context.Context, conn *grpc.ClientConn, args []string) error {
+	client, args, err := reflectionCommand(ctx, conn, "describe", args)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if len(args) == 0 {
+		return errors.New("describe needs a symbol, such as protos.UserService or protos.User")
+	}
+
+	for _, symbol := range args {
+		d, err := client.Resolve(symbol)
+		if err != nil {
+			return err
+		}
+		printDescriptor(os.Stdout, d)
+	}
+	return nil
+}
+
+// printDescriptor writes d in protobuf source syntax, naming types by their
+// fully-qualified names.
+func printDescriptor(w io.Writer, d protoreflect.Descriptor) {
+	switch d := d.(type) {
+	case protoreflect.ServiceDescriptor:
+		fmt.Fprintf(w, "%s is a service:\nservice %s {\n", d.FullName(), d.Name())
+		for i := 0; i < d.Methods().Len(); i++ {
+			fmt.Fprintf(w, "  %s\n", methodSignature(d.Methods().Get(i)))
+		}
+		fmt.Fprintln(w, "}")
+	case protoreflect.MethodDescriptor:
+		fmt.Fprintf(w, "%s is a method:\n%s\n", d.FullName(), methodSignature(d))
+	case protoreflect.MessageDescriptor:
+		fmt.Fprintf(w, "%s is a message:\n", d.FullName())
+		printMessage(w, d, "")
+	case protoreflect.EnumDescriptor:
+		fmt.Fprintf(w, "%s is an enum:\n", d.FullName())
+		printEnum(w, d, "")
+	case protoreflect.FieldDescriptor:
+		fmt.Fprintf(w, "%s is a field:\n%s\n", d.FullName(), fieldDefinition(d))
+	default:
+		fmt.Fprintf(w, "%s\n", d.FullName())
+	}
+}
+
+func methodSignature(m protoreflect.MethodDescriptor) string {
+	stream := func(streaming bool) string {
+		if streaming {
+			return "stream "
+		}
+		return ""
+	}
+	return fmt.Sprintf("rpc %s ( %s.%s ) returns ( %s.%s );", m.Name(),
+		stream(m.IsStreamingClient()), m.Input().FullName(),
+		stream(m.IsStreamingServer()), m.Output().FullName())
+}
+
+func printMessage(w io.Writer, m protoreflect.MessageDescriptor, indent string) {
+	fmt.Fprintf(w, "%smessage %s {\n", indent, m.Name())
+
+	for i := 0; i < m.Fields().Len(); i++ {
+		field := m.Fields().Get(i)
+		if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			// Print a oneof with its first field.
+			if oneof.Fields().Get(0) != field {
+				continue
+			}
+			fmt.Fprintf(w, "%s  oneof %s {\n", indent, oneof.Name())
+			for j := 0; j < oneof.Fields().Len(); j++ {
+				fmt.Fprintf(w, "%s    %s\n", indent, fieldDefinition(oneof.Fields().Get(j)))
+			}
+			fmt.Fprintf(w, "%s  }\n", indent)
+			continue
+		}
+		fmt.Fprintf(w, "%s  %s\n", indent, fieldDefinition(field))
+	}
+
+	for i := 0; i < m.Messages().Len(); i++ {
+		if nested := m.Messages().Get(i); !nested.IsMapEntry() {
+			printMessage(w, nested, indent+"  ")
+		}
+	}
+	for i := 0; i < m.Enums().Len(); i++ {
+		printEnum(w, m.Enums().Get(i), indent+"  ")
+	}
+
+	fmt.Fprintf(w, "%s}\n", indent)
+}
+
+func printEnum(w io.Writer, e protoreflect.EnumDescriptor, indent string) {
+	fmt.Fprintf(w, "%senum %s {\n", indent, e.Name())
+	for i := 0; i < e.Values().Len(); i++ {
+		value := e.Values().Get(i)
+		fmt.Fprintf(w, "%s  %s = %d;\n", indent, value.Name(), value.Number())
+	}
+	fmt.Fprintf(w, "%s}\n", indent)
+}
+
+func fieldDefinition(f protoreflect.FieldDescriptor) string {
+	label := ""
+	switch {
+	case f.IsMap():
+	case f.IsList():
+		label = "repeated "
+	case f.HasOptionalKeyword():
+		label = "optional "
+	}
+	return fmt.Sprintf("%s%s %s = %d;", label, fieldType(f), f.Name(), f.Number())
+}
+
+func fieldType(f protoreflect.FieldDescriptor) string {
+	if f.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", fieldType(f.MapKey()), fieldType(f.MapValue()))
+	}
+	switch f.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return "." + string(f.Message().FullName())
+	case protoreflect.EnumKind:
+		return "." + string(f.Enum().FullName())
+	default:
+		return f.Kind().String()
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// topLevelResolver only finds files and top-level symbols, like servers that
+// do not index methods and fields.
+type topLevelResolver struct {
+	*protoregistry.Files
+}
+
+func (r topLevelResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	d, err := r.Files.FindDescriptorByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := d.Parent().(protoreflect.FileDescriptor); !ok {
+		return nil, protoregistry.NotFound
+	}
+	return d, nil
+}
+
+// startReflectionServer serves the UserService with reflection, using the
+// standard resolver when resolver is nil, and opens a reflection client.
+func startReflectionServer(t *testing.T, resolver protodesc.Resolver) *reflectionClient {
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	protos.RegisterUserServiceServer(server, protos.UnimplementedUserServiceServer{})
+	if resolver == nil {
+		reflection.Register(server)
+	} else {
+		reflectionpb.RegisterServerReflectionServer(server, reflection.NewServerV1(reflection.ServerOptions{
+			Services:           server,
+			DescriptorResolver: resolver,
+		}))
+	}
+	go server.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	client, err := newReflectionClient(t.Context(), conn)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		client.Close()
+		conn.Close()
+		server.Stop()
+	})
+	return client
+}
+
+func describe(t *testing.T, client *reflectionClient, symbol string) string {
+	d, err := client.Resolve(symbol)
+	require.NoError(t, err)
+
+	var out strings.Builder
+	printDescriptor(&out, d)
+	return out.String()
+}
+
+func TestReflectionClient(t *testing.T) {
+	t.Run("lists the services", func(t *testing.T) {
+		client := startReflectionServer(t, nil)
+
+		services, err := client.ListServices()
+
+		assert.NoError(t, err)
+		assert.Contains(t, services, "protos.UserService")
+	})
+
+	t.Run("describes a service", func(t *testing.T) {
+		client := startReflectionServer(t, nil)
+
+		out := describe(t, client, "protos.UserService")
+
+		assert.True(t, strings.HasPrefix(out, "protos.UserService is a service:\nservice UserService {\n"))
+		assert.Contains(t, out, "  rpc GetUser ( .protos.GetUserRequest ) returns ( .protos.UserResponse );\n")
+		assert.Contains(t, out, "  rpc BulkCreateUsers ( stream .protos.CreateUserRequest ) returns ( .protos.BulkCreateUsersResponse );\n")
+	})
+
+	t.Run("describes a method", func(t *testing.T) {
+		client := startReflectionServer(t, nil)
+
+		out := describe(t, client, "protos.UserService.WatchUsers")
+
+		assert.Equal(t, "protos.UserService.WatchUsers is a method:\n"+
+			"rpc WatchUsers ( .protos.WatchUsersRequest ) returns ( stream .protos.UserEvent );\n", out)
+	})
+
+	t.Run("describes a nested enum", func(t *testing.T) {
+		client := startReflectionServer(t, nil)
+
+		out := describe(t, client, "protos.UserEvent.Type")
+
+		assert.Equal(t, "protos.UserEvent.Type is an enum:\n"+
+			"enum Type {\n"+
+			"  TYPE_UNSPECIFIED = 0;\n"+
+			"  CREATED = 1;\n"+
+			"  UPDATED = 2;\n"+
+			"  DELETED = 3;\n"+
+			"}\n", out)
+	})
+
+	t.Run("describes an optional field", func(t *testing.T) {
+		client := startReflectionServer(t, nil)
+
+		out := describe(t, client, "protos.UpdateUserRequest.email")
+
+		assert.Equal(t, "protos.UpdateUserRequest.email is a field:\noptional string email = 3;\n", out)
+	})
+
+	t.Run("describes a message with its nested enum", func(t *testing.T) {
+		client := startReflectionServer(t, nil)
+
+		out := describe(t, client, ".protos.UserEvent")
+
+		assert.Contains(t, out, "message UserEvent {\n  .protos.UserEvent.Type type = 1;\n")
+		assert.Contains(t, out, "  enum Type {\n    TYPE_UNSPECIFIED = 0;\n")
+	})
+
+	t.Run("falls back to the enclosing symbol", func(t *testing.T) {
+		client := startReflectionServer(t, topLevelResolver{protoregistry.GlobalFiles})
+
+		method := describe(t, client, "protos.UserService.UpdateUser")
+		field := describe(t, client, "protos.UpdateUserRequest.password")
+
+		assert.Equal(t, "protos.UserService.UpdateUser is a method:\n"+
+			"rpc UpdateUser ( .protos.UpdateUserRequest ) returns ( .protos.UserResponse );\n", method)
+		assert.Equal(t, "protos.UpdateUserRequest.password is a field:\noptional string password = 4;\n", field)
+	})
+
+	t.Run("fetches the dependencies the server does not resend", func(t *testing.T) {
+		client := startReflectionServer(t, nil)
+		_, err := client.Resolve("protos.User")
+		require.NoError(t, err)
+
+		// The server only sends each file once per stream, so a client that
+		// lost its files has to ask for the dependencies by name.
+		client.files = new(protoregistry.Files)
+		client.fetched = map[string]*descriptorpb.FileDescriptorProto{}
+
+		out := describe(t, client, "protos.User")
+
+		assert.Contains(t, out, "  .google.protobuf.Timestamp created_at = 6;\n")
+		_, err = client.files.FindFileByPath("google/protobuf/timestamp.proto")
+		assert.NoError(t, err)
+	})
+
+	t.Run("reports an unknown symbol as not found", func(t *testing.T) {
+		client := startReflectionServer(t, nil)
+
+		_, err := client.Resolve("protos.Missing")
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.ErrorContains(t, err, "cannot resolve protos.Missing")
+	})
+
+	t.Run("reports an unknown member of a known symbol as not found", func(t *testing.T) {
+		client := startReflectionServer(t, nil)
+
+		_, err := client.Resolve("protos.UserService.Missing")
+
+		assert.ErrorIs(t, err, protoregistry.NotFound)
+		assert.ErrorContains(t, err, "cannot resolve protos.UserService.Missing")
+	})
+}
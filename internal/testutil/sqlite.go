@@ -0,0 +1,30 @@
+// Package testutil holds helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// OpenMemoryDB opens an empty in-memory sqlite database that is closed when
+// the test ends.
+func OpenMemoryDB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to connect to the database: %v", err)
+	}
+
+	// Every connection to :memory: is a separate database.
+	ins, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get DB instance: %v", err)
+	}
+	ins.SetMaxOpenConns(1)
+	t.Cleanup(func() { ins.Close() })
+	return db
+}
@@ -0,0 +1,190 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrNoCertificates = errors.New("no certificates found in CA bundle")
+
+type ServerOptions struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mutual TLS: clients must present a certificate
+	// signed by one of these CAs.
+	ClientCAFile string
+}
+
+// Reloader serves the certificate and client CA bundle from disk and picks up
+// changes to the files on the next handshake, so certificates can be rotated
+// without a restart.
+type Reloader struct {
+	opts ServerOptions
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	stamps    []fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func NewReloader(opts ServerOptions) (*Reloader, error) {
+	r := &Reloader{opts: opts}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reloader) Reload() error {
+	stamps, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load key pair: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		if clientCAs, err = LoadCertPool(r.opts.ClientCAFile); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCAs, r.stamps = &cert, clientCAs, stamps
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Reloader) Config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.reloadIfChanged()
+			return r.current(), nil
+		},
+	}
+}
+
+func (r *Reloader) current() *tls.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2"},
+		Certificates: []tls.Certificate{*r.cert},
+	}
+	if r.clientCAs != nil {
+		cfg.ClientCAs = r.clientCAs
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg
+}
+
+func (r *Reloader) reloadIfChanged() {
+	stamps, err := r.stat()
+	if err != nil {
+		log.Printf("TLS reload skipped: %v", err)
+		return
+	}
+
+	r.mu.RLock()
+	changed := !sameStamps(stamps, r.stamps)
+	r.mu.RUnlock()
+	if !changed {
+		return
+	}
+
+	// A rotation may be caught half written; keep serving the previous
+	// certificate until the files form a valid pair again.
+	if err := r.Reload(); err != nil {
+		log.Printf("TLS reload failed, keeping the previous certificate: %v", err)
+	}
+}
+
+func (r *Reloader) stat() ([]fileStamp, error) {
+	files := []string{r.opts.CertFile, r.opts.KeyFile}
+	if r.opts.ClientCAFile != "" {
+		files = append(files, r.opts.ClientCAFile)
+	}
+
+	stamps := make([]fileStamp, len(files))
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		stamps[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+func sameStamps(a, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+type ClientOptions struct {
+	// CAFile verifies the server against these CAs instead of the system roots.
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+func ClientConfig(opts ClientOptions) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: opts.ServerName}
+
+	if opts.CAFile != "" {
+		pool, err := LoadCertPool(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client key pair: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+func LoadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: %w", file, ErrNoCertificates)
+	}
+	return pool, nil
+}
@@ -0,0 +1,255 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cndrsdrmn/go-grpc/internal/tlsconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue signs a leaf certificate valid for localhost and returns it and its
+// key PEM encoded.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) string {
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func serve(t *testing.T, cfg *tls.Config) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(cfg)))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	return lis.Addr().String()
+}
+
+func check(t *testing.T, addr string, opts tlsconfig.ClientOptions) (*x509.Certificate, error) {
+	cfg, err := tlsconfig.ClientConfig(opts)
+	require.NoError(t, err)
+
+	var peer *x509.Certificate
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		peer = cs.PeerCertificates[0]
+		return nil
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	return peer, err
+}
+
+func TestReloaderServesTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test CA")
+	cert, key := ca.issue(t, 10, x509.ExtKeyUsageServerAuth)
+
+	reloader, err := tlsconfig.NewReloader(tlsconfig.ServerOptions{
+		CertFile: writeFile(t, filepath.Join(dir, "server.crt"), cert),
+		KeyFile:  writeFile(t, filepath.Join(dir, "server.key"), key),
+	})
+	require.NoError(t, err)
+	addr := serve(t, reloader.Config())
+
+	caFile := writeFile(t, filepath.Join(dir, "ca.crt"), ca.pem)
+
+	t.Run("accepts a client trusting the CA", func(t *testing.T) {
+		peer, err := check(t, addr, tlsconfig.ClientOptions{CAFile: caFile})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(10), peer.SerialNumber.Int64())
+	})
+
+	t.Run("rejects a client trusting another CA", func(t *testing.T) {
+		other := writeFile(t, filepath.Join(dir, "other.crt"), newTestCA(t, "other CA").pem)
+
+		_, err := check(t, addr, tlsconfig.ClientOptions{CAFile: other})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestReloaderMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test CA")
+	serverCert, serverKey := ca.issue(t, 10, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, 20, x509.ExtKeyUsageClientAuth)
+	caFile := writeFile(t, filepath.Join(dir, "ca.crt"), ca.pem)
+
+	reloader, err := tlsconfig.NewReloader(tlsconfig.ServerOptions{
+		CertFile:     writeFile(t, filepath.Join(dir, "server.crt"), serverCert),
+		KeyFile:      writeFile(t, filepath.Join(dir, "server.key"), serverKey),
+		ClientCAFile: caFile,
+	})
+	require.NoError(t, err)
+	addr := serve(t, reloader.Config())
+
+	t.Run("accepts a client certificate signed by the CA", func(t *testing.T) {
+		_, err := check(t, addr, tlsconfig.ClientOptions{
+			CAFile:   caFile,
+			CertFile: writeFile(t, filepath.Join(dir, "client.crt"), clientCert),
+			KeyFile:  writeFile(t, filepath.Join(dir, "client.key"), clientKey),
+		})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects a client without a certificate", func(t *testing.T) {
+		_, err := check(t, addr, tlsconfig.ClientOptions{CAFile: caFile})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects a client certificate from another CA", func(t *testing.T) {
+		otherCert, otherKey := newTestCA(t, "other CA").issue(t, 30, x509.ExtKeyUsageClientAuth)
+
+		_, err := check(t, addr, tlsconfig.ClientOptions{
+			CAFile:   caFile,
+			CertFile: writeFile(t, filepath.Join(dir, "other.crt"), otherCert),
+			KeyFile:  writeFile(t, filepath.Join(dir, "other.key"), otherKey),
+		})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestReloaderHotReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test CA")
+	caFile := writeFile(t, filepath.Join(dir, "ca.crt"), ca.pem)
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+
+	cert, key := ca.issue(t, 10, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, cert)
+	writeFile(t, keyFile, key)
+
+	reloader, err := tlsconfig.NewReloader(tlsconfig.ServerOptions{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	addr := serve(t, reloader.Config())
+
+	peer, err := check(t, addr, tlsconfig.ClientOptions{CAFile: caFile})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), peer.SerialNumber.Int64())
+
+	rotate := func(cert, key []byte) {
+		writeFile(t, certFile, cert)
+		writeFile(t, keyFile, key)
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(certFile, later, later))
+		require.NoError(t, os.Chtimes(keyFile, later, later))
+	}
+
+	t.Run("serves a rotated certificate without a restart", func(t *testing.T) {
+		rotate(ca.issue(t, 11, x509.ExtKeyUsageServerAuth))
+
+		peer, err := check(t, addr, tlsconfig.ClientOptions{CAFile: caFile})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(11), peer.SerialNumber.Int64())
+	})
+
+	t.Run("keeps the previous certificate when the new pair is invalid", func(t *testing.T) {
+		next, _ := ca.issue(t, 12, x509.ExtKeyUsageServerAuth)
+		_, mismatched := ca.issue(t, 13, x509.ExtKeyUsageServerAuth)
+		rotate(next, mismatched)
+
+		peer, err := check(t, addr, tlsconfig.ClientOptions{CAFile: caFile})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(11), peer.SerialNumber.Int64())
+	})
+}
+
+func TestNewReloaderRejectsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, "test CA")
+	cert, key := ca.issue(t, 10, x509.ExtKeyUsageServerAuth)
+	certFile := writeFile(t, filepath.Join(dir, "server.crt"), cert)
+	keyFile := writeFile(t, filepath.Join(dir, "server.key"), key)
+
+	_, err := tlsconfig.NewReloader(tlsconfig.ServerOptions{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key")})
+	assert.Error(t, err)
+
+	_, err = tlsconfig.NewReloader(tlsconfig.ServerOptions{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientCAFile: writeFile(t, filepath.Join(dir, "empty.crt"), []byte("not a certificate")),
+	})
+	assert.ErrorIs(t, err, tlsconfig.ErrNoCertificates)
+}
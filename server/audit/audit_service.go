@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+type pageToken struct {
+	BeforeID     uint `json:"before_id"`
+	TargetUserID uint `json:"target_user_id,omitempty"`
+	ActorID      uint `json:"actor_id,omitempty"`
+}
+
+type AuditServiceInterface interface {
+	pb.AuditServiceServer
+}
+
+type auditService struct {
+	pb.UnimplementedAuditServiceServer
+	repo RepositoryInterface
+}
+
+func (srvs *auditService) ListAuditEvents(ctx context.Context, req *pb.ListAuditEventsRequest) (*pb.ListAuditEventsResponse, error) {
+	query := Query{TargetUserID: uint(req.TargetUserId), ActorID: uint(req.ActorId)}
+
+	if req.PageToken != "" {
+		token, err := decodePageToken(req.PageToken)
+		if err != nil || token.TargetUserID != query.TargetUserID || token.ActorID != query.ActorID {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+		query.BeforeID = token.BeforeID
+	}
+
+	size := int(req.PageSize)
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	size = min(size, maxPageSize)
+	query.Limit = size + 1
+
+	events, err := srvs.repo.List(query)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	res := &pb.ListAuditEventsResponse{}
+	if len(events) > size {
+		events = events[:size]
+		res.NextPageToken = encodePageToken(pageToken{
+			BeforeID:     events[size-1].ID,
+			TargetUserID: query.TargetUserID,
+			ActorID:      query.ActorID,
+		})
+	}
+
+	for _, event := range events {
+		res.Events = append(res.Events, event.ToProto())
+	}
+
+	return res, nil
+}
+
+func (srvs *auditService) VerifyAuditLog(ctx context.Context, req *pb.VerifyAuditLogRequest) (*pb.VerifyAuditLogResponse, error) {
+	result, err := srvs.repo.Verify()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.VerifyAuditLogResponse{
+		Valid:         result.Valid(),
+		CheckedEvents: uint64(result.Checked),
+		FirstBrokenId: uint64(result.BrokenID),
+		Reason:        result.Reason,
+		HeadHash:      result.HeadHash,
+	}, nil
+}
+
+func encodePageToken(token pageToken) string {
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodePageToken(s string) (pageToken, error) {
+	var token pageToken
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return token, err
+	}
+	if err := json.Unmarshal(raw, &token); err != nil {
+		return token, err
+	}
+	if token.BeforeID == 0 {
+		return token, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+	return token, nil
+}
+
+func NewAuditService(repo RepositoryInterface) AuditServiceInterface {
+	return &auditService{repo: repo}
+}
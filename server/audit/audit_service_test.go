@@ -0,0 +1,86 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/audit"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSrvsListAuditEvents(t *testing.T) {
+	defer teardownTest(t)
+	repo := audit.NewRepository(testDB)
+	srvs := audit.NewAuditService(repo)
+	ctx := context.Background()
+
+	for target := uint(1); target <= 5; target++ {
+		assert.NoError(t, repo.Record(&audit.Event{ActorID: 1, TargetUserID: target % 2, Method: "/protos.UserService/CreateUser", Outcome: audit.OutcomeOK}))
+	}
+
+	t.Run("walks every page", func(t *testing.T) {
+		var ids []uint64
+		req := &protos.ListAuditEventsRequest{PageSize: 2}
+		for {
+			res, err := srvs.ListAuditEvents(ctx, req)
+			assert.NoError(t, err)
+			for _, event := range res.Events {
+				ids = append(ids, event.Id)
+			}
+			if res.NextPageToken == "" {
+				break
+			}
+			req.PageToken = res.NextPageToken
+		}
+
+		assert.Len(t, ids, 5)
+		assert.IsDecreasing(t, ids)
+	})
+
+	t.Run("filters by target user", func(t *testing.T) {
+		res, err := srvs.ListAuditEvents(ctx, &protos.ListAuditEventsRequest{TargetUserId: 1})
+
+		assert.NoError(t, err)
+		assert.Len(t, res.Events, 3)
+	})
+
+	t.Run("rejects a page token issued for another filter", func(t *testing.T) {
+		res, err := srvs.ListAuditEvents(ctx, &protos.ListAuditEventsRequest{PageSize: 1, TargetUserId: 1})
+		assert.NoError(t, err)
+
+		_, err = srvs.ListAuditEvents(ctx, &protos.ListAuditEventsRequest{PageSize: 1, PageToken: res.NextPageToken})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = srvs.ListAuditEvents(ctx, &protos.ListAuditEventsRequest{PageToken: "not-a-token"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestSrvsVerifyAuditLog(t *testing.T) {
+	defer teardownTest(t)
+	repo := audit.NewRepository(testDB)
+	srvs := audit.NewAuditService(repo)
+	ctx := context.Background()
+
+	for target := uint(1); target <= 3; target++ {
+		assert.NoError(t, repo.Record(&audit.Event{ActorID: 1, TargetUserID: target, Method: "/protos.UserService/DeleteUser", Outcome: audit.OutcomeOK}))
+	}
+
+	res, err := srvs.VerifyAuditLog(ctx, &protos.VerifyAuditLogRequest{})
+	assert.NoError(t, err)
+	assert.True(t, res.Valid)
+	assert.Equal(t, uint64(3), res.CheckedEvents)
+	assert.NotEmpty(t, res.HeadHash)
+
+	assert.NoError(t, testDB.Exec("UPDATE audit_events SET target_user_id = 9 WHERE target_user_id = 2").Error)
+
+	res, err = srvs.VerifyAuditLog(ctx, &protos.VerifyAuditLogRequest{})
+	assert.NoError(t, err)
+	assert.False(t, res.Valid)
+	assert.Equal(t, uint64(1), res.CheckedEvents)
+	assert.NotZero(t, res.FirstBrokenId)
+	assert.NotEmpty(t, res.Reason)
+}
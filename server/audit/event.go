@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const OutcomeOK = "OK"
+
+type Event struct {
+	ID            uint `gorm:"primaryKey"`
+	CreatedAt     time.Time
+	ActorID       uint `gorm:"index"`
+	ActorEmail    string
+	Method        string `gorm:"not null"`
+	TargetUserID  uint   `gorm:"index"`
+	ChangedFields string
+	RequestID     string `gorm:"index"`
+	Outcome       string `gorm:"not null"`
+	PrevHash      string
+	Hash          string
+}
+
+func (Event) TableName() string {
+	return "audit_events"
+}
+
+func (event Event) Fields() []string {
+	if event.ChangedFields == "" {
+		return nil
+	}
+	return strings.Split(event.ChangedFields, ",")
+}
+
+// chainedEvent is the canonical form hashed into the chain; its field order
+// is fixed by the struct so the encoding is stable.
+type chainedEvent struct {
+	PrevHash      string `json:"prev_hash"`
+	ID            uint   `json:"id"`
+	CreatedAt     int64  `json:"created_at"`
+	ActorID       uint   `json:"actor_id"`
+	ActorEmail    string `json:"actor_email"`
+	Method        string `json:"method"`
+	TargetUserID  uint   `json:"target_user_id"`
+	ChangedFields string `json:"changed_fields"`
+	RequestID     string `json:"request_id"`
+	Outcome       string `json:"outcome"`
+}
+
+func (event Event) computeHash() string {
+	raw, _ := json.Marshal(chainedEvent{
+		PrevHash:      event.PrevHash,
+		ID:            event.ID,
+		CreatedAt:     event.CreatedAt.UnixNano(),
+		ActorID:       event.ActorID,
+		ActorEmail:    event.ActorEmail,
+		Method:        event.Method,
+		TargetUserID:  event.TargetUserID,
+		ChangedFields: event.ChangedFields,
+		RequestID:     event.RequestID,
+		Outcome:       event.Outcome,
+	})
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func (event Event) ToProto() *pb.AuditEvent {
+	return &pb.AuditEvent{
+		Id:            uint64(event.ID),
+		CreatedAt:     timestamppb.New(event.CreatedAt),
+		ActorId:       uint64(event.ActorID),
+		ActorEmail:    event.ActorEmail,
+		Method:        event.Method,
+		TargetUserId:  uint64(event.TargetUserID),
+		ChangedFields: event.Fields(),
+		RequestId:     event.RequestID,
+		Outcome:       event.Outcome,
+		PrevHash:      event.PrevHash,
+		Hash:          event.Hash,
+	}
+}
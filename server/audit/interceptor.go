@@ -0,0 +1,179 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"strings"
+
+	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/auth"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const RequestIDHeader = "x-request-id"
+
+var createdFields = []string{"name", "email", "password"}
+
+// mutation describes the target user and the changed field names of a
+// mutating call. resp is nil when the call failed.
+type mutation func(req, resp any) (uint64, []string)
+
+var mutations = map[string]mutation{
+	pb.UserService_CreateUser_FullMethodName: func(req, resp any) (uint64, []string) {
+		res, _ := resp.(*pb.UserResponse)
+		return res.GetUser().GetId(), createdFields
+	},
+	pb.UserService_UpdateUser_FullMethodName: func(req, resp any) (uint64, []string) {
+		r := req.(*pb.UpdateUserRequest)
+		fields, _ := users.UpdateFields(r)
+		return r.GetId(), fields
+	},
+	pb.UserService_DeleteUser_FullMethodName: func(req, resp any) (uint64, []string) {
+		return req.(*pb.DeleteUserRequest).GetId(), []string{"deleted_at"}
+	},
+	pb.UserService_RestoreUser_FullMethodName: func(req, resp any) (uint64, []string) {
+		return req.(*pb.RestoreUserRequest).GetId(), []string{"deleted_at"}
+	},
+	pb.UserService_PurgeUser_FullMethodName: func(req, resp any) (uint64, []string) {
+		return req.(*pb.PurgeUserRequest).GetId(), nil
+	},
+	pb.UserService_GrantRole_FullMethodName: func(req, resp any) (uint64, []string) {
+		return req.(*pb.RoleRequest).GetUserId(), []string{"roles"}
+	},
+	pb.UserService_RevokeRole_FullMethodName: func(req, resp any) (uint64, []string) {
+		return req.(*pb.RoleRequest).GetUserId(), []string{"roles"}
+	},
+}
+
+// Recorder writes an audit event for every mutating UserService call. It runs
+// before authentication so that calls without a valid token are recorded too,
+// with actor 0, and learns the caller of the others through
+// auth.ReserveIdentity.
+type Recorder struct {
+	repo RepositoryInterface
+}
+
+func NewRecorder(repo RepositoryInterface) *Recorder {
+	return &Recorder{repo: repo}
+}
+
+func (r *Recorder) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		describe, ok := mutations[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		ctx = auth.ReserveIdentity(ctx)
+		requestID := requestID(ctx)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID))
+
+		resp, err := handler(ctx, req)
+
+		result := resp
+		if err != nil {
+			result = nil
+		}
+
+		target, fields := describe(req, result)
+		r.record(ctx, info.FullMethod, requestID, target, fields, outcome(err))
+		return resp, err
+	}
+}
+
+func (r *Recorder) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if info.FullMethod != pb.UserService_BulkCreateUsers_FullMethodName {
+			return handler(srv, ss)
+		}
+
+		ctx := auth.ReserveIdentity(ss.Context())
+		requestID := requestID(ctx)
+		ss.SetHeader(metadata.Pairs(RequestIDHeader, requestID))
+
+		stream := &recordingServerStream{ServerStream: ss, ctx: ctx}
+		err := handler(srv, stream)
+		if stream.response == nil {
+			r.record(ctx, info.FullMethod, requestID, 0, createdFields, outcome(err))
+			return err
+		}
+
+		for _, result := range stream.response.Results {
+			rowOutcome := OutcomeOK
+			if result.ErrorReason != "" {
+				rowOutcome = result.ErrorReason
+			}
+			r.record(ctx, info.FullMethod, requestID, result.Id, createdFields, rowOutcome)
+		}
+		return err
+	}
+}
+
+func (r *Recorder) record(ctx context.Context, method, requestID string, target uint64, fields []string, outcome string) {
+	event := &Event{
+		Method:        method,
+		TargetUserID:  uint(target),
+		ChangedFields: strings.Join(fields, ","),
+		RequestID:     requestID,
+		Outcome:       outcome,
+	}
+	if identity, ok := auth.IdentityFromContext(ctx); ok {
+		event.ActorID = identity.UserID
+		event.ActorEmail = identity.Email
+	}
+
+	// The mutation has already been applied, so a failure here must not turn
+	// into an error for the caller.
+	if err := r.repo.Record(event); err != nil {
+		log.Printf("Failed to record audit event for %s (request %s): %v", method, requestID, err)
+	}
+}
+
+func requestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(RequestIDHeader); len(values) > 0 && values[0] != "" {
+		return values[0]
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func outcome(err error) string {
+	if err == nil {
+		return OutcomeOK
+	}
+
+	st := status.Convert(err)
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return code.Code(st.Code()).String()
+}
+
+type recordingServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	response *pb.BulkCreateUsersResponse
+}
+
+func (s *recordingServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *recordingServerStream) SendMsg(m any) error {
+	if res, ok := m.(*pb.BulkCreateUsersResponse); ok {
+		s.response = res
+	}
+	return s.ServerStream.SendMsg(m)
+}
@@ -0,0 +1,136 @@
+package audit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/audit"
+	"github.com/cndrsdrmn/go-grpc/server/auth"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+type fakeRepository struct {
+	events []*audit.Event
+	err    error
+}
+
+func (repo *fakeRepository) Record(event *audit.Event) error {
+	repo.events = append(repo.events, event)
+	return repo.err
+}
+
+func (repo *fakeRepository) List(audit.Query) ([]audit.Event, error) {
+	return nil, nil
+}
+
+func (repo *fakeRepository) Verify() (audit.Verification, error) {
+	return audit.Verification{}, nil
+}
+
+func TestRecorderUnary(t *testing.T) {
+	repo := &fakeRepository{}
+	interceptor := audit.NewRecorder(repo).Unary()
+
+	ctx := auth.WithIdentity(context.Background(), &auth.Identity{UserID: 1, Email: "admin@example.com"})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(audit.RequestIDHeader, "req-1"))
+
+	call := func(method string, req any, resp any, err error) *audit.Event {
+		repo.events = nil
+		handler := func(context.Context, any) (any, error) { return resp, err }
+		interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		if len(repo.events) == 0 {
+			return nil
+		}
+		return repo.events[0]
+	}
+
+	t.Run("records a created user", func(t *testing.T) {
+		event := call(protos.UserService_CreateUser_FullMethodName,
+			&protos.CreateUserRequest{Name: "John", Email: "john@example.com", Password: "secret"},
+			&protos.UserResponse{User: &protos.User{Id: 7}}, nil)
+
+		assert.Equal(t, &audit.Event{
+			ActorID:       1,
+			ActorEmail:    "admin@example.com",
+			Method:        protos.UserService_CreateUser_FullMethodName,
+			TargetUserID:  7,
+			ChangedFields: "name,email,password",
+			RequestID:     "req-1",
+			Outcome:       audit.OutcomeOK,
+		}, event)
+	})
+
+	t.Run("records the masked fields of an update without values", func(t *testing.T) {
+		password := "new-secret"
+		event := call(protos.UserService_UpdateUser_FullMethodName,
+			&protos.UpdateUserRequest{Id: 7, Password: &password, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"password"}}},
+			nil, nil)
+
+		assert.Equal(t, uint(7), event.TargetUserID)
+		assert.Equal(t, "password", event.ChangedFields)
+		assert.NotContains(t, event.ChangedFields, password)
+	})
+
+	t.Run("records the outcome of a failed call", func(t *testing.T) {
+		event := call(protos.UserService_DeleteUser_FullMethodName,
+			&protos.DeleteUserRequest{Id: 7},
+			(*protos.DeleteUserResponse)(nil), status.Error(codes.PermissionDenied, "denied"))
+
+		assert.Equal(t, uint(7), event.TargetUserID)
+		assert.Equal(t, "PERMISSION_DENIED", event.Outcome)
+	})
+
+	t.Run("ignores read-only calls", func(t *testing.T) {
+		assert.Nil(t, call(protos.UserService_GetUser_FullMethodName, &protos.GetUserRequest{Id: 7}, nil, nil))
+	})
+
+	t.Run("does not fail the call when recording fails", func(t *testing.T) {
+		repo.err = errors.New("disk full")
+		defer func() { repo.err = nil }()
+
+		handler := func(context.Context, any) (any, error) { return &protos.UserResponse{}, nil }
+		_, err := interceptor(ctx, &protos.RestoreUserRequest{Id: 7}, &grpc.UnaryServerInfo{FullMethod: protos.UserService_RestoreUser_FullMethodName}, handler)
+
+		assert.NoError(t, err)
+	})
+}
+
+type fakeBulkStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeBulkStream) Context() context.Context    { return s.ctx }
+func (s *fakeBulkStream) SetHeader(metadata.MD) error { return nil }
+func (s *fakeBulkStream) SendMsg(any) error           { return nil }
+
+func TestRecorderStream(t *testing.T) {
+	repo := &fakeRepository{}
+	interceptor := audit.NewRecorder(repo).Stream()
+	info := &grpc.StreamServerInfo{FullMethod: protos.UserService_BulkCreateUsers_FullMethodName, IsClientStream: true}
+
+	handler := func(srv any, ss grpc.ServerStream) error {
+		return ss.SendMsg(&protos.BulkCreateUsersResponse{Results: []*protos.BulkCreateUserResult{
+			{Index: 0, Id: 3},
+			{Index: 1, ErrorReason: "EMAIL_ALREADY_EXISTS"},
+		}})
+	}
+
+	err := interceptor(nil, &fakeBulkStream{ctx: context.Background()}, info, handler)
+
+	assert.NoError(t, err)
+	if assert.Len(t, repo.events, 2) {
+		assert.Equal(t, uint(3), repo.events[0].TargetUserID)
+		assert.Equal(t, audit.OutcomeOK, repo.events[0].Outcome)
+		assert.Equal(t, "EMAIL_ALREADY_EXISTS", repo.events[1].Outcome)
+		assert.Equal(t, repo.events[0].RequestID, repo.events[1].RequestID)
+		assert.NotEmpty(t, repo.events[0].RequestID)
+	}
+}
@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"sync"
+	"time"
+)
+
+// memoryRepository keeps the audit log in process memory, for servers started
+// with in-memory storage. Events are held in ID order.
+type memoryRepository struct {
+	mu     sync.RWMutex
+	events []Event
+	now    func() time.Time
+}
+
+func (repo *memoryRepository) Record(event *Event) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	event.ID = uint(len(repo.events)) + 1
+	event.CreatedAt = repo.now().UTC().Truncate(time.Microsecond)
+	event.PrevHash = ""
+	if len(repo.events) > 0 {
+		event.PrevHash = repo.events[len(repo.events)-1].Hash
+	}
+	event.Hash = event.computeHash()
+
+	repo.events = append(repo.events, *event)
+	return nil
+}
+
+func (repo *memoryRepository) List(query Query) ([]Event, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	events := []Event{}
+	for i := len(repo.events) - 1; i >= 0 && (query.Limit < 0 || len(events) < query.Limit); i-- {
+		event := repo.events[i]
+		switch {
+		case query.TargetUserID != 0 && event.TargetUserID != query.TargetUserID:
+		case query.ActorID != 0 && event.ActorID != query.ActorID:
+		case query.BeforeID != 0 && event.ID >= query.BeforeID:
+		default:
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (repo *memoryRepository) Verify() (Verification, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var result Verification
+	for i := range repo.events {
+		result.check(&repo.events[i])
+	}
+	return result, nil
+}
+
+func NewMemoryRepository() RepositoryInterface {
+	return &memoryRepository{now: time.Now}
+}
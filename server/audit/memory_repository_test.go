@@ -0,0 +1,43 @@
+package audit_test
+
+import (
+	"testing"
+
+	"github.com/cndrsdrmn/go-grpc/server/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRepo(t *testing.T) {
+	repo := audit.NewMemoryRepository()
+
+	for _, event := range []*audit.Event{
+		{ActorID: 1, TargetUserID: 2, Method: "/protos.UserService/CreateUser", Outcome: audit.OutcomeOK},
+		{ActorID: 1, TargetUserID: 3, Method: "/protos.UserService/CreateUser", Outcome: audit.OutcomeOK},
+		{ActorID: 2, TargetUserID: 2, Method: "/protos.UserService/UpdateUser", Outcome: audit.OutcomeOK},
+	} {
+		assert.NoError(t, repo.Record(event))
+	}
+
+	t.Run("lists newest first with filters", func(t *testing.T) {
+		events, err := repo.List(audit.Query{TargetUserID: 2, Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, uint(3), events[0].ID)
+
+		events, err = repo.List(audit.Query{BeforeID: 3, Limit: 1})
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+		assert.Equal(t, uint(2), events[0].ID)
+	})
+
+	t.Run("chains every event", func(t *testing.T) {
+		events, _ := repo.List(audit.Query{Limit: 10})
+		assert.Equal(t, events[1].Hash, events[0].PrevHash)
+
+		result, err := repo.Verify()
+		assert.NoError(t, err)
+		assert.True(t, result.Valid())
+		assert.Equal(t, 3, result.Checked)
+		assert.Equal(t, events[0].Hash, result.HeadHash)
+	})
+}
@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const verifyBatchSize = 500
+
+type Query struct {
+	TargetUserID uint
+	ActorID      uint
+	// BeforeID continues a listing below the last event of the previous page.
+	BeforeID uint
+	Limit    int
+}
+
+type Verification struct {
+	Checked  int
+	BrokenID uint
+	Reason   string
+	HeadHash string
+}
+
+func (v Verification) Valid() bool {
+	return v.BrokenID == 0
+}
+
+// check extends the verification by the next event in ID order. Events after
+// the first broken link are ignored.
+func (v *Verification) check(event *Event) {
+	switch {
+	case v.BrokenID != 0:
+	case event.PrevHash != v.HeadHash:
+		v.BrokenID, v.Reason = event.ID, "previous hash does not match the preceding event"
+	case event.Hash != event.computeHash():
+		v.BrokenID, v.Reason = event.ID, "hash does not match the event contents"
+	default:
+		v.Checked++
+		v.HeadHash = event.Hash
+	}
+}
+
+type RepositoryInterface interface {
+	Record(event *Event) error
+	List(query Query) ([]Event, error)
+	Verify() (Verification, error)
+}
+
+type repository struct {
+	db  *gorm.DB
+	now func() time.Time
+	// mu serializes appends so that each event links to the one before it.
+	// The chain assumes this process is the only writer.
+	mu sync.Mutex
+}
+
+func (repo *repository) Record(event *Event) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var last Event
+		if err := tx.Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+
+		event.ID = last.ID + 1
+		event.CreatedAt = repo.now().UTC().Truncate(time.Microsecond)
+		event.PrevHash = last.Hash
+		event.Hash = event.computeHash()
+
+		return tx.Create(event).Error
+	})
+}
+
+func (repo *repository) List(query Query) ([]Event, error) {
+	db := repo.db.Model(&Event{})
+	if query.TargetUserID != 0 {
+		db = db.Where("target_user_id = ?", query.TargetUserID)
+	}
+	if query.ActorID != 0 {
+		db = db.Where("actor_id = ?", query.ActorID)
+	}
+	if query.BeforeID != 0 {
+		db = db.Where("id < ?", query.BeforeID)
+	}
+
+	var events []Event
+	err := db.Order("id DESC").Limit(query.Limit).Find(&events).Error
+	return events, err
+}
+
+func (repo *repository) Verify() (Verification, error) {
+	var result Verification
+	var batch []Event
+	err := repo.db.FindInBatches(&batch, verifyBatchSize, func(tx *gorm.DB, n int) error {
+		for i := range batch {
+			result.check(&batch[i])
+		}
+		return nil
+	}).Error
+	return result, err
+}
+
+func NewRepository(db *gorm.DB) RepositoryInterface {
+	return &repository{db: db, now: time.Now}
+}
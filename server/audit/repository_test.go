@@ -0,0 +1,146 @@
+package audit_test
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"github.com/cndrsdrmn/go-grpc/server/audit"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testDB *gorm.DB
+
+func teardownTest(t *testing.T) {
+	if err := testDB.Exec("DELETE FROM audit_events").Error; err != nil {
+		t.Fatalf("failed to clear audit_events table: %v", err)
+	}
+}
+
+func TestMain(m *testing.M) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("failed to connect to the database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&audit.Event{}); err != nil {
+		log.Fatalf("failed to migrate database: %v", err)
+	}
+
+	testDB = db
+
+	os.Exit(m.Run())
+}
+
+func TestRepoList(t *testing.T) {
+	defer teardownTest(t)
+	repo := audit.NewRepository(testDB)
+
+	for _, event := range []*audit.Event{
+		{ActorID: 1, TargetUserID: 2, Method: "/protos.UserService/CreateUser", Outcome: audit.OutcomeOK},
+		{ActorID: 1, TargetUserID: 3, Method: "/protos.UserService/CreateUser", Outcome: audit.OutcomeOK},
+		{ActorID: 2, TargetUserID: 2, Method: "/protos.UserService/UpdateUser", ChangedFields: "name,email", Outcome: audit.OutcomeOK},
+	} {
+		assert.NoError(t, repo.Record(event))
+	}
+
+	t.Run("lists newest first", func(t *testing.T) {
+		events, err := repo.List(audit.Query{Limit: 10})
+
+		assert.NoError(t, err)
+		assert.Len(t, events, 3)
+		assert.Equal(t, []string{"name", "email"}, events[0].Fields())
+		assert.Nil(t, events[1].Fields())
+	})
+
+	t.Run("filters by target and actor", func(t *testing.T) {
+		events, err := repo.List(audit.Query{TargetUserID: 2, Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+
+		events, err = repo.List(audit.Query{TargetUserID: 2, ActorID: 1, Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("continues before an event", func(t *testing.T) {
+		first, err := repo.List(audit.Query{Limit: 1})
+		assert.NoError(t, err)
+
+		rest, err := repo.List(audit.Query{BeforeID: first[0].ID, Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, rest, 2)
+		assert.Less(t, rest[0].ID, first[0].ID)
+	})
+}
+
+func TestRepoVerify(t *testing.T) {
+	repo := audit.NewRepository(testDB)
+
+	record := func(n int) []audit.Event {
+		teardownTest(t)
+		for i := 0; i < n; i++ {
+			assert.NoError(t, repo.Record(&audit.Event{ActorID: 1, TargetUserID: uint(i + 1), Method: "/protos.UserService/CreateUser", Outcome: audit.OutcomeOK}))
+		}
+		events, err := repo.List(audit.Query{Limit: n})
+		assert.NoError(t, err)
+		return events
+	}
+	defer teardownTest(t)
+
+	t.Run("links every event to the previous one", func(t *testing.T) {
+		events := record(3)
+
+		assert.Empty(t, events[2].PrevHash)
+		assert.Equal(t, events[2].Hash, events[1].PrevHash)
+		assert.Equal(t, events[1].Hash, events[0].PrevHash)
+
+		result, err := repo.Verify()
+		assert.NoError(t, err)
+		assert.True(t, result.Valid())
+		assert.Equal(t, 3, result.Checked)
+		assert.Equal(t, events[0].Hash, result.HeadHash)
+	})
+
+	t.Run("accepts an empty log", func(t *testing.T) {
+		record(0)
+
+		result, err := repo.Verify()
+		assert.NoError(t, err)
+		assert.True(t, result.Valid())
+		assert.Zero(t, result.Checked)
+	})
+
+	t.Run("detects an edited event", func(t *testing.T) {
+		events := record(3)
+		assert.NoError(t, testDB.Exec("UPDATE audit_events SET outcome = 'PERMISSION_DENIED' WHERE id = ?", events[1].ID).Error)
+
+		result, err := repo.Verify()
+		assert.NoError(t, err)
+		assert.False(t, result.Valid())
+		assert.Equal(t, events[1].ID, result.BrokenID)
+		assert.Equal(t, 1, result.Checked)
+		assert.Equal(t, "hash does not match the event contents", result.Reason)
+	})
+
+	t.Run("detects a removed event", func(t *testing.T) {
+		events := record(3)
+		assert.NoError(t, testDB.Exec("DELETE FROM audit_events WHERE id = ?", events[1].ID).Error)
+
+		result, err := repo.Verify()
+		assert.NoError(t, err)
+		assert.Equal(t, events[0].ID, result.BrokenID)
+		assert.Equal(t, "previous hash does not match the preceding event", result.Reason)
+	})
+
+	t.Run("detects a rewritten hash", func(t *testing.T) {
+		events := record(2)
+		assert.NoError(t, testDB.Exec("UPDATE audit_events SET outcome = 'X', hash = 'forged' WHERE id = ?", events[1].ID).Error)
+
+		result, err := repo.Verify()
+		assert.NoError(t, err)
+		assert.Equal(t, events[1].ID, result.BrokenID)
+	})
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const tokenType = "Bearer"
+
+var errInvalidCredentials = status.Error(codes.Unauthenticated, "invalid email or password")
+
+// dummyHash is compared against when the email is unknown so that a failed
+// login takes the same time whether or not the account exists.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy-password"), users.PasswordCost())
+	return hash
+})
+
+// repositoryError reports a failed repository call as Internal unless the
+// request itself was cancelled or ran out of time.
+func repositoryError(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+type AuthServiceInterface interface {
+	pb.AuthServiceServer
+}
+
+type authService struct {
+	pb.UnimplementedAuthServiceServer
+	repo   users.UserRepositoryInterface
+	tokens *TokenManager
+}
+
+func (srvs *authService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	user, err := srvs.repo.FindUserByEmail(ctx, req.Email)
+	if errors.Is(err, users.ErrUserNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(req.Password))
+		return nil, errInvalidCredentials
+	}
+	if err != nil {
+		return nil, repositoryError(ctx)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return nil, errInvalidCredentials
+	}
+
+	token, err := srvs.tokens.Issue(user.ID, user.Email)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.LoginResponse{
+		AccessToken: token,
+		TokenType:   tokenType,
+		ExpiresIn:   int64(srvs.tokens.TTL().Seconds()),
+	}, nil
+}
+
+func NewAuthService(repo users.UserRepositoryInterface, tokens *TokenManager) AuthServiceInterface {
+	return &authService{repo: repo, tokens: tokens}
+}
@@ -0,0 +1,82 @@
+package auth_test
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/auth"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testDB *gorm.DB
+
+func TestMain(m *testing.M) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("failed to connect to the database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&users.User{}, &users.UserRole{}); err != nil {
+		log.Fatalf("failed to migrate database: %v", err)
+	}
+
+	testDB = db
+
+	os.Exit(m.Run())
+}
+
+func TestLogin(t *testing.T) {
+	repo := users.NewUserRepository(testDB)
+	tokens := auth.NewTokenManager([]byte("test-signing-key"), time.Hour)
+	srvs := auth.NewAuthService(repo, tokens)
+	ctx := context.Background()
+
+	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
+	assert.NoError(t, repo.CreateUser(ctx, user))
+
+	t.Run("issues a token for valid credentials", func(t *testing.T) {
+		res, err := srvs.Login(ctx, &protos.LoginRequest{Email: "john@example.com", Password: "secret"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer", res.TokenType)
+		assert.Equal(t, int64(3600), res.ExpiresIn)
+
+		claims, err := tokens.Verify(res.AccessToken)
+		assert.NoError(t, err)
+		id, _ := claims.UserID()
+		assert.Equal(t, user.ID, id)
+	})
+
+	t.Run("accepts a password changed through an update", func(t *testing.T) {
+		assert.NoError(t, repo.UpdateUser(ctx, user.ID, &users.User{Password: "changed"}, []string{"password"}))
+
+		_, err := srvs.Login(ctx, &protos.LoginRequest{Email: "john@example.com", Password: "changed"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejects a wrong password and an unknown email alike", func(t *testing.T) {
+		_, wrongPassword := srvs.Login(ctx, &protos.LoginRequest{Email: "john@example.com", Password: "wrong"})
+		_, unknownEmail := srvs.Login(ctx, &protos.LoginRequest{Email: "jane@example.com", Password: "secret"})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(wrongPassword))
+		assert.Equal(t, status.Convert(wrongPassword).Proto(), status.Convert(unknownEmail).Proto())
+	})
+
+	t.Run("reports a cancelled request instead of an internal error", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := srvs.Login(ctx, &protos.LoginRequest{Email: "john@example.com", Password: "changed"})
+
+		assert.Equal(t, codes.Canceled, status.Code(err))
+	})
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+)
+
+// BootstrapAdmins grants the admin role at startup to the accounts with the
+// given emails, first creating those that do not exist when password is set.
+// It does nothing once any user is an admin, so the list cannot hand the role
+// out again after admins manage roles with GrantRole and RevokeRole. Accounts
+// are created and promoted through srvs so that watchers see the changes.
+func BootstrapAdmins(ctx context.Context, repo users.UserRepositoryInterface, srvs pb.UserServiceServer, emails []string, password string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+
+	exists, err := repo.HasRole(ctx, users.RoleAdmin)
+	if err != nil || exists {
+		return err
+	}
+
+	for _, email := range emails {
+		id, err := bootstrapAccount(ctx, repo, srvs, email, password)
+		if err != nil {
+			return fmt.Errorf("bootstrap admin %s: %w", email, err)
+		}
+		if id == 0 {
+			slog.Warn("Bootstrap admin has no account and auth.bootstrap_password is not set", "email", email)
+			continue
+		}
+
+		if _, err := srvs.GrantRole(ctx, &pb.RoleRequest{UserId: id, Role: pb.Role_ROLE_ADMIN}); err != nil {
+			return fmt.Errorf("bootstrap admin %s: %w", email, err)
+		}
+		slog.Info("Granted admin to bootstrap admin", "email", email)
+	}
+	return nil
+}
+
+// bootstrapAccount returns the id of the account with email, creating it when
+// password is set. It returns 0 when there is no account to promote.
+func bootstrapAccount(ctx context.Context, repo users.UserRepositoryInterface, srvs pb.UserServiceServer, email, password string) (uint64, error) {
+	user, err := repo.FindUserByEmail(ctx, email)
+	if err == nil {
+		return uint64(user.ID), nil
+	}
+	if !errors.Is(err, users.ErrUserNotFound) {
+		return 0, err
+	}
+	if password == "" {
+		return 0, nil
+	}
+
+	name, _, _ := strings.Cut(email, "@")
+	res, err := srvs.CreateUser(ctx, &pb.CreateUserRequest{Name: name, Email: email, Password: password})
+	if err != nil {
+		return 0, err
+	}
+	return res.User.Id, nil
+}
@@ -0,0 +1,73 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/auth"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestBootstrapAdmins(t *testing.T) {
+	ctx := context.Background()
+	admins := []string{"admin@example.com", "ops@example.com"}
+
+	t.Run("promotes existing accounts and skips missing ones without a password", func(t *testing.T) {
+		repo := users.NewMemoryUserRepository()
+		admin := &users.User{Name: "Admin", Email: "admin@example.com", Password: "secret"}
+		require.NoError(t, repo.CreateUser(ctx, admin))
+
+		require.NoError(t, auth.BootstrapAdmins(ctx, repo, users.NewUserService(repo), admins, ""))
+
+		roles, err := repo.FindRoles(ctx, admin.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{users.RoleAdmin}, roles)
+
+		_, err = repo.FindUserByEmail(ctx, "ops@example.com")
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+	})
+
+	t.Run("seeds missing accounts with the bootstrap password", func(t *testing.T) {
+		repo := users.NewMemoryUserRepository()
+
+		require.NoError(t, auth.BootstrapAdmins(ctx, repo, users.NewUserService(repo), admins, "secret"))
+
+		for _, email := range admins {
+			user, err := repo.FindUserByEmail(ctx, email)
+			require.NoError(t, err)
+			assert.Equal(t, []string{users.RoleAdmin}, user.RoleNames())
+		}
+	})
+
+	t.Run("grants nothing once an admin exists", func(t *testing.T) {
+		repo := users.NewMemoryUserRepository()
+		srvs := users.NewUserService(repo)
+		owner := &users.User{Name: "Owner", Email: "owner@example.com", Password: "secret"}
+		require.NoError(t, repo.CreateUser(ctx, owner))
+		require.NoError(t, repo.GrantRole(ctx, owner.ID, users.RoleAdmin))
+
+		// A user may change their own email to one in the list.
+		user := &users.User{Name: "John", Email: "john@example.com", Password: "secret"}
+		require.NoError(t, repo.CreateUser(ctx, user))
+		_, err := srvs.UpdateUser(ctx, &protos.UpdateUserRequest{
+			Id:         uint64(user.ID),
+			Email:      proto.String("admin@example.com"),
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, auth.BootstrapAdmins(ctx, repo, srvs, admins, "secret"))
+
+		roles, err := repo.FindRoles(ctx, user.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, roles)
+
+		_, err = repo.FindUserByEmail(ctx, "ops@example.com")
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+	})
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+var DefaultPublicMethods = []string{
+	pb.AuthService_Login_FullMethodName,
+	healthpb.Health_Check_FullMethodName,
+	healthpb.Health_List_FullMethodName,
+	healthpb.Health_Watch_FullMethodName,
+	reflectionpb.ServerReflection_ServerReflectionInfo_FullMethodName,
+	reflectionv1alpha.ServerReflection_ServerReflectionInfo_FullMethodName,
+}
+
+type Identity struct {
+	UserID uint
+	Email  string
+}
+
+type identityKey struct{}
+
+type identitySlotKey struct{}
+
+type identitySlot struct {
+	identity *Identity
+}
+
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	if slot, ok := ctx.Value(identitySlotKey{}).(*identitySlot); ok {
+		slot.identity = identity
+	}
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// ReserveIdentity returns a context that also learns the identity attached
+// by WithIdentity on any context derived from it, so an interceptor running
+// before authentication can still tell who the caller was.
+func ReserveIdentity(ctx context.Context) context.Context {
+	return context.WithValue(ctx, identitySlotKey{}, &identitySlot{})
+}
+
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	if identity, ok := ctx.Value(identityKey{}).(*Identity); ok {
+		return identity, true
+	}
+	slot, ok := ctx.Value(identitySlotKey{}).(*identitySlot)
+	if !ok || slot.identity == nil {
+		return nil, false
+	}
+	return slot.identity, true
+}
+
+type Interceptor struct {
+	tokens *TokenManager
+	public map[string]bool
+}
+
+func NewInterceptor(tokens *TokenManager, publicMethods []string) *Interceptor {
+	public := make(map[string]bool, len(publicMethods))
+	for _, method := range publicMethods {
+		public[method] = true
+	}
+	return &Interceptor{tokens: tokens, public: public}
+}
+
+func (i *Interceptor) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := i.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (i *Interceptor) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (i *Interceptor) authenticate(ctx context.Context, method string) (context.Context, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		if i.public[method] {
+			return ctx, nil
+		}
+		return nil, err
+	}
+
+	claims, err := i.tokens.Verify(token)
+	if err != nil {
+		if i.public[method] {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	id, _ := claims.UserID()
+	return WithIdentity(ctx, &Identity{UserID: id, Email: claims.Email}), nil
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, tokenType) || token == "" {
+		return "", status.Error(codes.Unauthenticated, "authorization metadata must be a bearer token")
+	}
+
+	return token, nil
+}
+
+type authenticatedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedServerStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,84 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cndrsdrmn/go-grpc/server/auth"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func incomingContext(authorization string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", authorization))
+}
+
+func TestInterceptorUnary(t *testing.T) {
+	tokens := auth.NewTokenManager([]byte("test-signing-key"), time.Hour)
+	interceptor := auth.NewInterceptor(tokens, []string{"/test.Service/Public"}).Unary()
+
+	var identity *auth.Identity
+	handler := func(ctx context.Context, req any) (any, error) {
+		identity, _ = auth.IdentityFromContext(ctx)
+		return nil, nil
+	}
+
+	private := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Private"}
+	public := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Public"}
+
+	t.Run("attaches the caller identity", func(t *testing.T) {
+		token, _ := tokens.Issue(7, "john@example.com")
+
+		_, err := interceptor(incomingContext("Bearer "+token), nil, private, handler)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &auth.Identity{UserID: 7, Email: "john@example.com"}, identity)
+	})
+
+	t.Run("rejects missing and malformed credentials", func(t *testing.T) {
+		for _, ctx := range []context.Context{
+			context.Background(),
+			incomingContext("Basic dXNlcjpwYXNz"),
+			incomingContext("Bearer "),
+			incomingContext("Bearer not-a-token"),
+		} {
+			_, err := interceptor(ctx, nil, private, handler)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		}
+	})
+
+	t.Run("fills a reserved identity for earlier interceptors", func(t *testing.T) {
+		token, _ := tokens.Issue(7, "john@example.com")
+		ctx := auth.ReserveIdentity(incomingContext("Bearer " + token))
+
+		_, err := interceptor(ctx, nil, private, handler)
+		assert.NoError(t, err)
+
+		reserved, ok := auth.IdentityFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, &auth.Identity{UserID: 7, Email: "john@example.com"}, reserved)
+	})
+
+	t.Run("leaves a reserved identity empty when rejected", func(t *testing.T) {
+		ctx := auth.ReserveIdentity(context.Background())
+
+		_, err := interceptor(ctx, nil, private, handler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		_, ok := auth.IdentityFromContext(ctx)
+		assert.False(t, ok)
+	})
+
+	t.Run("allows public methods anonymously", func(t *testing.T) {
+		identity = nil
+
+		_, err := interceptor(context.Background(), nil, public, handler)
+
+		assert.NoError(t, err)
+		assert.Nil(t, identity)
+	})
+}
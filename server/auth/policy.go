@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+)
+
+// Rule describes who may call a method. A caller is allowed when any of the
+// conditions holds; Self only applies to unary requests carrying the target
+// user's id.
+type Rule struct {
+	Authenticated bool
+	Self          bool
+	Roles         []string
+}
+
+type Policy map[string]Rule
+
+var (
+	staff     = []string{users.RoleAdmin, users.RoleManager}
+	adminOnly = []string{users.RoleAdmin}
+)
+
+var DefaultPolicy = Policy{
+	pb.AuthService_Login_FullMethodName:            {Authenticated: true},
+	pb.UserService_CreateUser_FullMethodName:       {Authenticated: true},
+	healthpb.Health_Check_FullMethodName:           {Authenticated: true},
+	healthpb.Health_List_FullMethodName:            {Authenticated: true},
+	healthpb.Health_Watch_FullMethodName:           {Authenticated: true},
+	pb.UserService_GetUser_FullMethodName:          {Self: true, Roles: staff},
+	pb.UserService_UpdateUser_FullMethodName:       {Self: true, Roles: adminOnly},
+	pb.UserService_AllUsers_FullMethodName:         {Roles: staff},
+	pb.UserService_ListUsers_FullMethodName:        {Roles: staff},
+	pb.UserService_StreamUsers_FullMethodName:      {Roles: staff},
+	pb.UserService_WatchUsers_FullMethodName:       {Roles: staff},
+	pb.UserService_BulkCreateUsers_FullMethodName:  {Roles: staff},
+	pb.UserService_DeleteUser_FullMethodName:       {Roles: adminOnly},
+	pb.UserService_RestoreUser_FullMethodName:      {Roles: staff},
+	pb.UserService_PurgeUser_FullMethodName:        {Roles: adminOnly},
+	pb.UserService_GrantRole_FullMethodName:        {Roles: adminOnly},
+	pb.UserService_RevokeRole_FullMethodName:       {Roles: adminOnly},
+	pb.AuditService_ListAuditEvents_FullMethodName: {Roles: adminOnly},
+	pb.AuditService_VerifyAuditLog_FullMethodName:  {Roles: adminOnly},
+
+	// Reflection is only served when enabled in the configuration.
+	reflectionpb.ServerReflection_ServerReflectionInfo_FullMethodName:      {Authenticated: true},
+	reflectionv1alpha.ServerReflection_ServerReflectionInfo_FullMethodName: {Authenticated: true},
+}
+
+type RoleLookup func(ctx context.Context, userID uint) ([]string, error)
+
+type Authorizer struct {
+	policy Policy
+	roles  RoleLookup
+}
+
+func NewAuthorizer(policy Policy, roles RoleLookup) *Authorizer {
+	return &Authorizer{policy: policy, roles: roles}
+}
+
+func (a *Authorizer) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := a.authorize(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (a *Authorizer) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := a.authorize(ss.Context(), info.FullMethod, nil); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (a *Authorizer) authorize(ctx context.Context, method string, req any) error {
+	// Without an identity the authentication interceptor has already decided
+	// the method is public.
+	identity, ok := IdentityFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	rule, ok := a.policy[method]
+	if !ok {
+		return permissionDenied(method)
+	}
+
+	if rule.Authenticated || (rule.Self && isSelf(identity, req)) {
+		return nil
+	}
+
+	if len(rule.Roles) == 0 {
+		return permissionDenied(method)
+	}
+
+	roles, err := a.roles(ctx, identity.UserID)
+	if err != nil {
+		return repositoryError(ctx)
+	}
+
+	for _, role := range rule.Roles {
+		if slices.Contains(roles, role) {
+			return nil
+		}
+	}
+
+	return permissionDenied(method)
+}
+
+func isSelf(identity *Identity, req any) bool {
+	target, ok := req.(interface{ GetId() uint64 })
+	return ok && target.GetId() == uint64(identity.UserID)
+}
+
+func permissionDenied(method string) error {
+	return status.Errorf(codes.PermissionDenied, "permission denied for %s", method)
+}
@@ -0,0 +1,99 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/auth"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthorizerUnary(t *testing.T) {
+	roles := map[uint][]string{
+		1: {users.RoleAdmin},
+		2: {users.RoleManager},
+	}
+	lookup := func(ctx context.Context, id uint) ([]string, error) {
+		if id == 99 {
+			return nil, errors.New("database is down")
+		}
+		return roles[id], nil
+	}
+	interceptor := auth.NewAuthorizer(auth.DefaultPolicy, lookup).Unary()
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	call := func(id uint, method string, req any) error {
+		ctx := context.Background()
+		if id != 0 {
+			ctx = auth.WithIdentity(ctx, &auth.Identity{UserID: id})
+		}
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	t.Run("only admins can delete users", func(t *testing.T) {
+		req := &protos.DeleteUserRequest{Id: 3}
+
+		assert.NoError(t, call(1, protos.UserService_DeleteUser_FullMethodName, req))
+		assert.Equal(t, codes.PermissionDenied, status.Code(call(2, protos.UserService_DeleteUser_FullMethodName, req)))
+		assert.Equal(t, codes.PermissionDenied, status.Code(call(3, protos.UserService_DeleteUser_FullMethodName, req)))
+	})
+
+	t.Run("users may update only themselves", func(t *testing.T) {
+		assert.NoError(t, call(3, protos.UserService_UpdateUser_FullMethodName, &protos.UpdateUserRequest{Id: 3}))
+		assert.Equal(t, codes.PermissionDenied, status.Code(call(3, protos.UserService_UpdateUser_FullMethodName, &protos.UpdateUserRequest{Id: 4})))
+		assert.Equal(t, codes.PermissionDenied, status.Code(call(2, protos.UserService_UpdateUser_FullMethodName, &protos.UpdateUserRequest{Id: 4})))
+		assert.NoError(t, call(1, protos.UserService_UpdateUser_FullMethodName, &protos.UpdateUserRequest{Id: 4}))
+	})
+
+	t.Run("managers can read other users", func(t *testing.T) {
+		assert.NoError(t, call(2, protos.UserService_GetUser_FullMethodName, &protos.GetUserRequest{Id: 4}))
+		assert.Equal(t, codes.PermissionDenied, status.Code(call(3, protos.UserService_GetUser_FullMethodName, &protos.GetUserRequest{Id: 4})))
+	})
+
+	t.Run("only admins can change roles", func(t *testing.T) {
+		req := &protos.RoleRequest{UserId: 2, Role: protos.Role_ROLE_ADMIN}
+
+		assert.NoError(t, call(1, protos.UserService_GrantRole_FullMethodName, req))
+		assert.Equal(t, codes.PermissionDenied, status.Code(call(2, protos.UserService_GrantRole_FullMethodName, req)))
+		assert.Equal(t, codes.PermissionDenied, status.Code(call(2, protos.UserService_RevokeRole_FullMethodName, req)))
+	})
+
+	t.Run("denies methods missing from the policy", func(t *testing.T) {
+		assert.Equal(t, codes.PermissionDenied, status.Code(call(1, "/test.Service/Unknown", nil)))
+	})
+
+	t.Run("lets anonymous callers through to public methods", func(t *testing.T) {
+		assert.NoError(t, call(0, protos.UserService_CreateUser_FullMethodName, &protos.CreateUserRequest{}))
+	})
+
+	t.Run("fails closed when roles cannot be loaded", func(t *testing.T) {
+		assert.Equal(t, codes.Internal, status.Code(call(99, protos.UserService_DeleteUser_FullMethodName, &protos.DeleteUserRequest{Id: 3})))
+	})
+}
+
+func TestAuthorizerStream(t *testing.T) {
+	lookup := func(ctx context.Context, id uint) ([]string, error) { return nil, nil }
+	interceptor := auth.NewAuthorizer(auth.DefaultPolicy, lookup).Stream()
+	handler := func(srv any, ss grpc.ServerStream) error { return nil }
+
+	ctx := auth.WithIdentity(context.Background(), &auth.Identity{UserID: 3})
+	err := interceptor(nil, &contextStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: protos.UserService_WatchUsers_FullMethodName}, handler)
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
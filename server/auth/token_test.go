@@ -0,0 +1,49 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cndrsdrmn/go-grpc/server/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenManagerIssueAndVerify(t *testing.T) {
+	tokens := auth.NewTokenManager([]byte("test-signing-key"), time.Hour)
+
+	token, err := tokens.Issue(42, "john@example.com")
+	assert.NoError(t, err)
+
+	claims, err := tokens.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "john@example.com", claims.Email)
+
+	id, err := claims.UserID()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), id)
+}
+
+func TestTokenManagerVerifyRejects(t *testing.T) {
+	tokens := auth.NewTokenManager([]byte("test-signing-key"), time.Hour)
+	token, _ := tokens.Issue(42, "john@example.com")
+
+	t.Run("a token signed with another key", func(t *testing.T) {
+		other := auth.NewTokenManager([]byte("other-signing-key"), time.Hour)
+
+		_, err := other.Verify(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("an expired token", func(t *testing.T) {
+		expired := auth.NewTokenManager([]byte("test-signing-key"), -time.Minute)
+		token, _ := expired.Issue(42, "john@example.com")
+
+		_, err := tokens.Verify(token)
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+
+	t.Run("a malformed token", func(t *testing.T) {
+		_, err := tokens.Verify("not-a-token")
+		assert.ErrorIs(t, err, auth.ErrInvalidToken)
+	})
+}
@@ -0,0 +1,298 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cndrsdrmn/go-grpc/server/auth"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	StorageSQLite = "sqlite"
+	StorageMemory = "memory"
+)
+
+// ConfigFileEnv names the environment variable read when -config is not given.
+const ConfigFileEnv = "CONFIG_FILE"
+
+type Config struct {
+	ListenAddr  string        `yaml:"listen_addr"`
+	Storage     string        `yaml:"storage"`
+	DatabaseDSN string        `yaml:"database_dsn"`
+	LogLevel    string        `yaml:"log_level"`
+	BcryptCost  int           `yaml:"bcrypt_cost"`
+	Reflection  bool          `yaml:"reflection"`
+	TLS         TLSConfig     `yaml:"tls"`
+	Auth        AuthConfig    `yaml:"auth"`
+	Timeouts    TimeoutConfig `yaml:"timeouts"`
+}
+
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+type AuthConfig struct {
+	SigningKey        string        `yaml:"signing_key"`
+	TokenTTL          time.Duration `yaml:"token_ttl"`
+	PublicMethods     []string      `yaml:"public_methods"`
+	OpenSignup        bool          `yaml:"open_signup"`
+	BootstrapAdmins   []string      `yaml:"bootstrap_admins"`
+	BootstrapPassword string        `yaml:"bootstrap_password"`
+}
+
+type TimeoutConfig struct {
+	Connection  time.Duration `yaml:"connection"`
+	Shutdown    time.Duration `yaml:"shutdown"`
+	HealthCheck time.Duration `yaml:"health_check"`
+}
+
+func Default() Config {
+	return Config{
+		ListenAddr:  ":50051",
+		Storage:     StorageSQLite,
+		DatabaseDSN: "database.sqlite",
+		LogLevel:    "info",
+		BcryptCost:  bcrypt.DefaultCost,
+		Auth: AuthConfig{
+			TokenTTL:      auth.DefaultTokenTTL,
+			PublicMethods: slices.Clone(auth.DefaultPublicMethods),
+		},
+		Timeouts: TimeoutConfig{
+			Connection:  120 * time.Second,
+			Shutdown:    10 * time.Second,
+			HealthCheck: 5 * time.Second,
+		},
+	}
+}
+
+// setting binds one configuration value to its environment variable and
+// command-line flag. key is the value's path in the config file; isBool lets
+// the flag be given without a value, meaning true.
+type setting struct {
+	key    string
+	env    string
+	flag   string
+	usage  string
+	set    func(cfg *Config, raw string) error
+	isBool bool
+}
+
+var settings = []setting{
+	{"listen_addr", "LISTEN_ADDR", "listen", "address to listen on", stringValue(func(c *Config) *string { return &c.ListenAddr }), false},
+	{"storage", "STORAGE", "storage", "sqlite, or memory to keep data in process memory only", stringValue(func(c *Config) *string { return &c.Storage }), false},
+	{"database_dsn", "DATABASE_DSN", "dsn", "sqlite database DSN", stringValue(func(c *Config) *string { return &c.DatabaseDSN }), false},
+	{"log_level", "LOG_LEVEL", "log-level", "one of debug, info, warn, error", stringValue(func(c *Config) *string { return &c.LogLevel }), false},
+	{"bcrypt_cost", "BCRYPT_COST", "bcrypt-cost", "bcrypt cost for new password hashes", intValue(func(c *Config) *int { return &c.BcryptCost }), false},
+	{"reflection", "REFLECTION", "reflection", "serve gRPC reflection so tools can list services and schemas", boolValue(func(c *Config) *bool { return &c.Reflection }), true},
+	{"tls.cert_file", "TLS_CERT_FILE", "tls-cert", "server certificate; enables TLS", stringValue(func(c *Config) *string { return &c.TLS.CertFile }), false},
+	{"tls.key_file", "TLS_KEY_FILE", "tls-key", "server private key", stringValue(func(c *Config) *string { return &c.TLS.KeyFile }), false},
+	{"tls.client_ca_file", "TLS_CLIENT_CA_FILE", "tls-client-ca", "CA bundle required of client certificates", stringValue(func(c *Config) *string { return &c.TLS.ClientCAFile }), false},
+	{"auth.signing_key", "AUTH_SIGNING_KEY", "", "", stringValue(func(c *Config) *string { return &c.Auth.SigningKey }), false},
+	{"auth.token_ttl", "AUTH_TOKEN_TTL", "token-ttl", "lifetime of issued access tokens", durationValue(func(c *Config) *time.Duration { return &c.Auth.TokenTTL }), false},
+	{"auth.public_methods", "AUTH_PUBLIC_METHODS", "public-methods", "comma-separated methods callable without a token", listValue(func(c *Config) *[]string { return &c.Auth.PublicMethods }), false},
+	{"auth.open_signup", "AUTH_OPEN_SIGNUP", "open-signup", "let anyone call CreateUser without a token", boolValue(func(c *Config) *bool { return &c.Auth.OpenSignup }), true},
+	{"auth.bootstrap_admins", "AUTH_BOOTSTRAP_ADMINS", "bootstrap-admins", "comma-separated emails granted admin at startup while no user is an admin", listValue(func(c *Config) *[]string { return &c.Auth.BootstrapAdmins }), false},
+	{"auth.bootstrap_password", "AUTH_BOOTSTRAP_PASSWORD", "", "", stringValue(func(c *Config) *string { return &c.Auth.BootstrapPassword }), false},
+	{"timeouts.connection", "CONNECTION_TIMEOUT", "connection-timeout", "deadline for establishing a connection", durationValue(func(c *Config) *time.Duration { return &c.Timeouts.Connection }), false},
+	{"timeouts.shutdown", "SHUTDOWN_TIMEOUT", "shutdown-timeout", "grace period for in-flight calls on shutdown", durationValue(func(c *Config) *time.Duration { return &c.Timeouts.Shutdown }), false},
+	{"timeouts.health_check", "HEALTH_CHECK_INTERVAL", "health-check-interval", "interval and deadline of database health checks", durationValue(func(c *Config) *time.Duration { return &c.Timeouts.HealthCheck }), false},
+}
+
+// Load builds the configuration from, in increasing order of precedence, the
+// defaults, the config file, environment variables and command-line flags.
+// The config file is taken from -config or CONFIG_FILE and is optional.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", "", "YAML config file (default $"+ConfigFileEnv+")")
+	flags := map[string]string{}
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		record := func(raw string) error {
+			flags[s.flag] = raw
+			return nil
+		}
+		if s.isBool {
+			fs.BoolFunc(s.flag, s.usage+" ($"+s.env+")", record)
+		} else {
+			fs.Func(s.flag, s.usage+" ($"+s.env+")", record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	if *path == "" {
+		*path, _ = lookupEnv(ConfigFileEnv)
+	}
+	if *path != "" {
+		if err := loadFile(&cfg, *path); err != nil {
+			return cfg, err
+		}
+	}
+
+	var errs []error
+	for _, s := range settings {
+		raw, ok := lookupEnv(s.env)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := s.set(&cfg, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s from environment variable %s=%q: %w", s.key, s.env, raw, err))
+		}
+	}
+
+	for _, s := range settings {
+		raw, ok := flags[s.flag]
+		if !ok {
+			continue
+		}
+		if err := s.set(&cfg, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s from flag -%s=%q: %w", s.key, s.flag, raw, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return cfg, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func loadFile(cfg *Config, path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("cannot read config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every invalid value at once, naming each by its config
+// file key.
+func (cfg Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if cfg.ListenAddr == "" {
+		invalid("listen_addr must not be empty")
+	}
+	if cfg.Storage != StorageSQLite && cfg.Storage != StorageMemory {
+		invalid("storage must be one of %s, %s, got %q", StorageSQLite, StorageMemory, cfg.Storage)
+	}
+	if cfg.DatabaseDSN == "" {
+		invalid("database_dsn must not be empty")
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		invalid("log_level must be one of debug, info, warn, error, got %q", cfg.LogLevel)
+	}
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		invalid("bcrypt_cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cfg.BcryptCost)
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		invalid("tls.cert_file and tls.key_file must be set together")
+	}
+	if cfg.TLS.ClientCAFile != "" && cfg.TLS.CertFile == "" {
+		invalid("tls.client_ca_file requires tls.cert_file and tls.key_file")
+	}
+	if cfg.Auth.TokenTTL <= 0 {
+		invalid("auth.token_ttl must be positive, got %s", cfg.Auth.TokenTTL)
+	}
+	if cfg.Timeouts.Connection <= 0 {
+		invalid("timeouts.connection must be positive, got %s", cfg.Timeouts.Connection)
+	}
+	if cfg.Timeouts.Shutdown < 0 {
+		invalid("timeouts.shutdown must not be negative, got %s", cfg.Timeouts.Shutdown)
+	}
+	if cfg.Timeouts.HealthCheck <= 0 {
+		invalid("timeouts.health_check must be positive, got %s", cfg.Timeouts.HealthCheck)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Level returns the parsed log level; it is only meaningful after Validate.
+func (cfg Config) Level() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(cfg.LogLevel))
+	return level
+}
+
+func stringValue(field func(*Config) *string) func(*Config, string) error {
+	return func(cfg *Config, raw string) error {
+		*field(cfg) = raw
+		return nil
+	}
+}
+
+func intValue(field func(*Config) *int) func(*Config, string) error {
+	return func(cfg *Config, raw string) error {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+		*field(cfg) = n
+		return nil
+	}
+}
+
+func boolValue(field func(*Config) *bool) func(*Config, string) error {
+	return func(cfg *Config, raw string) error {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("not a boolean, use true or false")
+		}
+		*field(cfg) = b
+		return nil
+	}
+}
+
+func durationValue(field func(*Config) *time.Duration) func(*Config, string) error {
+	return func(cfg *Config, raw string) error {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return errors.New("not a duration, use a value such as 30s or 5m")
+		}
+		*field(cfg) = d
+		return nil
+	}
+}
+
+func listValue(field func(*Config) *[]string) func(*Config, string) error {
+	return func(cfg *Config, raw string) error {
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*field(cfg) = items
+		return nil
+	}
+}
@@ -0,0 +1,183 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cndrsdrmn/go-grpc/server/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := config.Load(nil, env(nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+	assert.Equal(t, ":50051", cfg.ListenAddr)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+listen_addr: ":6000"
+database_dsn: file.sqlite
+bcrypt_cost: 6
+reflection: true
+auth:
+  token_ttl: 30m
+  bootstrap_admins: [root@example.com]
+timeouts:
+  shutdown: 1s
+`)
+
+	t.Run("file overrides defaults", func(t *testing.T) {
+		cfg, err := config.Load([]string{"-config", path}, env(nil))
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":6000", cfg.ListenAddr)
+		assert.Equal(t, 6, cfg.BcryptCost)
+		assert.True(t, cfg.Reflection)
+		assert.Equal(t, 30*time.Minute, cfg.Auth.TokenTTL)
+		assert.Equal(t, []string{"root@example.com"}, cfg.Auth.BootstrapAdmins)
+		assert.Equal(t, time.Second, cfg.Timeouts.Shutdown)
+		assert.Equal(t, "info", cfg.LogLevel)
+	})
+
+	t.Run("environment overrides the file", func(t *testing.T) {
+		cfg, err := config.Load(nil, env(map[string]string{
+			config.ConfigFileEnv:    path,
+			"LISTEN_ADDR":           ":7000",
+			"REFLECTION":            "false",
+			"AUTH_BOOTSTRAP_ADMINS": "a@example.com, b@example.com",
+			"LOG_LEVEL":             "",
+		}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":7000", cfg.ListenAddr)
+		assert.Equal(t, "file.sqlite", cfg.DatabaseDSN)
+		assert.False(t, cfg.Reflection)
+		assert.Equal(t, []string{"a@example.com", "b@example.com"}, cfg.Auth.BootstrapAdmins)
+		assert.Equal(t, "info", cfg.LogLevel)
+	})
+
+	t.Run("flags override the environment", func(t *testing.T) {
+		cfg, err := config.Load([]string{"-config", path, "-listen", ":8000", "-log-level", "debug"}, env(map[string]string{
+			"LISTEN_ADDR": ":7000",
+		}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, ":8000", cfg.ListenAddr)
+		assert.Equal(t, "debug", cfg.LogLevel)
+	})
+
+	t.Run("boolean flags need no value", func(t *testing.T) {
+		cfg, err := config.Load([]string{"-reflection", "-open-signup", "-listen", ":8000"}, env(nil))
+
+		assert.NoError(t, err)
+		assert.True(t, cfg.Reflection)
+		assert.True(t, cfg.Auth.OpenSignup)
+		assert.Equal(t, ":8000", cfg.ListenAddr)
+
+		cfg, err = config.Load([]string{"-config", path, "-reflection=false"}, env(nil))
+
+		assert.NoError(t, err)
+		assert.False(t, cfg.Reflection)
+	})
+}
+
+func TestLoadRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		file    string
+		message string
+	}{
+		{
+			name:    "a malformed environment value",
+			env:     map[string]string{"BCRYPT_COST": "high"},
+			message: `bcrypt_cost from environment variable BCRYPT_COST="high": not an integer`,
+		},
+		{
+			name:    "a malformed boolean",
+			args:    []string{"-reflection=yes please"},
+			message: `reflection from flag -reflection="yes please": not a boolean`,
+		},
+		{
+			name:    "a malformed flag value",
+			args:    []string{"-shutdown-timeout", "soon"},
+			message: `timeouts.shutdown from flag -shutdown-timeout="soon": not a duration`,
+		},
+		{
+			name:    "an unknown key in the file",
+			file:    "listen: \":1\"\n",
+			message: "field listen not found",
+		},
+		{
+			name:    "an out of range value",
+			args:    []string{"-bcrypt-cost", "40"},
+			message: "bcrypt_cost must be between 4 and 31, got 40",
+		},
+		{
+			name:    "an unknown log level",
+			env:     map[string]string{"LOG_LEVEL": "loud"},
+			message: `log_level must be one of debug, info, warn, error, got "loud"`,
+		},
+		{
+			name:    "an unknown storage",
+			args:    []string{"-storage", "postgres"},
+			message: `storage must be one of sqlite, memory, got "postgres"`,
+		},
+		{
+			name:    "a non-positive health check interval",
+			env:     map[string]string{"HEALTH_CHECK_INTERVAL": "0s"},
+			message: "timeouts.health_check must be positive, got 0s",
+		},
+		{
+			name:    "a certificate without a key",
+			env:     map[string]string{"TLS_CERT_FILE": "server.crt"},
+			message: "tls.cert_file and tls.key_file must be set together",
+		},
+		{
+			name:    "a missing config file",
+			args:    []string{"-config", "does-not-exist.yaml"},
+			message: "cannot read config file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append(args, "-config", writeConfig(t, tt.file))
+			}
+
+			_, err := config.Load(args, env(tt.env))
+
+			assert.ErrorContains(t, err, tt.message)
+		})
+	}
+
+	t.Run("every invalid value at once", func(t *testing.T) {
+		_, err := config.Load([]string{"-listen", "", "-token-ttl", "0s"}, env(map[string]string{"DATABASE_DSN": ""}))
+
+		assert.ErrorContains(t, err, "listen_addr must not be empty")
+		assert.ErrorContains(t, err, "auth.token_ttl must be positive")
+	})
+}
@@ -0,0 +1,213 @@
+// Package filter parses the subset of the AIP-160 filtering language used by
+// list RPCs into an AST that callers translate into their own queries.
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Operator string
+
+const (
+	OpEqual        Operator = "="
+	OpNotEqual     Operator = "!="
+	OpLess         Operator = "<"
+	OpLessEqual    Operator = "<="
+	OpGreater      Operator = ">"
+	OpGreaterEqual Operator = ">="
+)
+
+type Expr interface {
+	String() string
+}
+
+type And struct {
+	Exprs []Expr
+}
+
+type Or struct {
+	Exprs []Expr
+}
+
+type Not struct {
+	Expr Expr
+}
+
+type Comparison struct {
+	Field string
+	Op    Operator
+	Value string
+}
+
+func (e And) String() string { return join(e.Exprs, " AND ") }
+
+func (e Or) String() string { return join(e.Exprs, " OR ") }
+
+func (e Not) String() string { return "NOT " + e.Expr.String() }
+
+func (e Comparison) String() string { return fmt.Sprintf("%s %s %q", e.Field, e.Op, e.Value) }
+
+func join(exprs []Expr, sep string) string {
+	parts := make([]string, 0, len(exprs))
+	for _, e := range exprs {
+		parts = append(parts, "("+e.String()+")")
+	}
+	return strings.Join(parts, sep)
+}
+
+// Parse returns nil for an empty filter. As in AIP-160, OR binds tighter
+// than AND, so `a = 1 AND b = 2 OR c = 3` means `a = 1 AND (b = 2 OR c = 3)`.
+func Parse(input string) (Expr, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == tokenIdent && tok.text == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expression() (Expr, error) {
+	var exprs []Expr
+	for {
+		expr, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+
+		if p.keyword("AND") {
+			continue
+		}
+
+		if tok := p.peek(); tok.kind == tokenEOF || tok.kind == tokenRParen {
+			break
+		}
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return And{Exprs: exprs}, nil
+}
+
+func (p *parser) factor() (Expr, error) {
+	var exprs []Expr
+	for {
+		expr, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+
+		if !p.keyword("OR") {
+			break
+		}
+	}
+
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return Or{Exprs: exprs}, nil
+}
+
+func (p *parser) term() (Expr, error) {
+	negated := p.keyword("NOT")
+	if !negated && p.peek().kind == tokenMinus {
+		p.next()
+		negated = true
+	}
+
+	if negated {
+		expr, err := p.simple()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: expr}, nil
+	}
+	return p.simple()
+}
+
+func (p *parser) simple() (Expr, error) {
+	if p.peek().kind == tokenLParen {
+		p.next()
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokenRParen {
+			return nil, fmt.Errorf("expected \")\" at position %d", tok.pos)
+		}
+		return expr, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (Expr, error) {
+	field := p.next()
+	if field.kind != tokenIdent || isKeyword(field.text) {
+		return nil, fmt.Errorf("expected a field name at position %d", field.pos)
+	}
+
+	op := p.next()
+	if op.kind != tokenOperator {
+		return nil, fmt.Errorf("expected a comparison operator after %q", field.text)
+	}
+
+	switch Operator(op.text) {
+	case OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual:
+	default:
+		return nil, fmt.Errorf("unsupported operator %q at position %d", op.text, op.pos)
+	}
+
+	value := p.next()
+	if value.kind != tokenString && (value.kind != tokenIdent || isKeyword(value.text)) {
+		return nil, fmt.Errorf("expected a value after %q", field.text+" "+op.text)
+	}
+
+	return Comparison{Field: field.text, Op: Operator(op.text), Value: value.text}, nil
+}
+
+func isKeyword(word string) bool {
+	return word == "AND" || word == "OR" || word == "NOT"
+}
@@ -0,0 +1,82 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/cndrsdrmn/go-grpc/server/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("empty filter", func(t *testing.T) {
+		expr, err := filter.Parse("  ")
+
+		assert.NoError(t, err)
+		assert.Nil(t, expr)
+	})
+
+	t.Run("single comparison", func(t *testing.T) {
+		expr, err := filter.Parse(`name = "Al*"`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, filter.Comparison{Field: "name", Op: filter.OpEqual, Value: "Al*"}, expr)
+	})
+
+	t.Run("bare values", func(t *testing.T) {
+		expr, err := filter.Parse(`email!=*@example.com`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, filter.Comparison{Field: "email", Op: filter.OpNotEqual, Value: "*@example.com"}, expr)
+	})
+
+	t.Run("OR binds tighter than AND", func(t *testing.T) {
+		expr, err := filter.Parse(`a = 1 AND b = 2 OR c = 3`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, filter.And{Exprs: []filter.Expr{
+			filter.Comparison{Field: "a", Op: filter.OpEqual, Value: "1"},
+			filter.Or{Exprs: []filter.Expr{
+				filter.Comparison{Field: "b", Op: filter.OpEqual, Value: "2"},
+				filter.Comparison{Field: "c", Op: filter.OpEqual, Value: "3"},
+			}},
+		}}, expr)
+	})
+
+	t.Run("negation and grouping", func(t *testing.T) {
+		expr, err := filter.Parse(`NOT (a >= "x" b < "y") -c <= "z"`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, filter.And{Exprs: []filter.Expr{
+			filter.Not{Expr: filter.And{Exprs: []filter.Expr{
+				filter.Comparison{Field: "a", Op: filter.OpGreaterEqual, Value: "x"},
+				filter.Comparison{Field: "b", Op: filter.OpLess, Value: "y"},
+			}}},
+			filter.Not{Expr: filter.Comparison{Field: "c", Op: filter.OpLessEqual, Value: "z"}},
+		}}, expr)
+	})
+
+	t.Run("escaped quotes", func(t *testing.T) {
+		expr, err := filter.Parse(`name = "say \"hi\""`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, `say "hi"`, expr.(filter.Comparison).Value)
+	})
+
+	t.Run("syntax errors", func(t *testing.T) {
+		for _, input := range []string{
+			`name`,
+			`name =`,
+			`name : "x"`,
+			`name = "x`,
+			`(name = "x"`,
+			`name = "x")`,
+			`AND = "x"`,
+			`name = "x" AND`,
+			`name ! "x"`,
+			`name = 'x'`,
+		} {
+			_, err := filter.Parse(input)
+			assert.Error(t, err, input)
+		}
+	})
+}
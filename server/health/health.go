@@ -0,0 +1,107 @@
+// Package health serves the standard grpc.health.v1 service and keeps the
+// reported status in line with the database the server depends on.
+package health
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Pinger checks that a dependency is reachable.
+type Pinger func(ctx context.Context) error
+
+// Checker reports every registered service, and the server as a whole under
+// the empty service name, as SERVING while ping succeeds and NOT_SERVING
+// otherwise. A nil ping always succeeds.
+type Checker struct {
+	server *health.Server
+	ping   Pinger
+
+	mu       sync.Mutex
+	services []string
+	healthy  bool
+}
+
+func NewChecker(ping Pinger) *Checker {
+	return &Checker{server: health.NewServer(), ping: ping, healthy: true}
+}
+
+// Register serves the health service on server. It must be called after the
+// other services are registered, since those are the ones it reports on.
+func (c *Checker) Register(server *grpc.Server) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name := range server.GetServiceInfo() {
+		c.services = append(c.services, name)
+	}
+	slices.Sort(c.services)
+	healthpb.RegisterHealthServer(server, c.server)
+	c.publish()
+}
+
+// Check pings once and updates the reported status.
+func (c *Checker) Check(ctx context.Context) {
+	var err error
+	if c.ping != nil {
+		err = c.ping(ctx)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if healthy := err == nil; healthy != c.healthy {
+		if healthy {
+			slog.Info("Health check recovered")
+		} else {
+			slog.Warn("Health check failed", "err", err)
+		}
+		c.healthy = healthy
+	}
+	c.publish()
+}
+
+// Run checks every interval, each ping bounded by the interval, until ctx
+// is done.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pingCtx, cancel := context.WithTimeout(ctx, interval)
+		c.Check(pingCtx)
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown reports NOT_SERVING for good so that load balancers stop sending
+// new calls while in-flight ones drain.
+func (c *Checker) Shutdown() {
+	c.server.Shutdown()
+}
+
+// publish must be called with mu held.
+func (c *Checker) publish() {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if c.healthy {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+
+	c.server.SetServingStatus("", status)
+	for _, name := range c.services {
+		c.server.SetServingStatus(name, status)
+	}
+}
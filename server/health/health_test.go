@@ -0,0 +1,90 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cndrsdrmn/go-grpc/server/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testService = "test.EchoService"
+
+func setupChecker(t *testing.T, ping health.Pinger) (*health.Checker, healthpb.HealthClient) {
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{ServiceName: testService, HandlerType: (*any)(nil)}, struct{}{})
+
+	checker := health.NewChecker(ping)
+	checker.Register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return checker, healthpb.NewHealthClient(conn)
+}
+
+func status(t *testing.T, client healthpb.HealthClient, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	res, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return res.Status
+}
+
+func TestChecker(t *testing.T) {
+	var failing atomic.Bool
+	checker, client := setupChecker(t, func(context.Context) error {
+		if failing.Load() {
+			return errors.New("database is unreachable")
+		}
+		return nil
+	})
+
+	t.Run("reports the server and every registered service", func(t *testing.T) {
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, client, ""))
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, client, testService))
+	})
+
+	t.Run("follows the ping", func(t *testing.T) {
+		failing.Store(true)
+		checker.Check(t.Context())
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, client, ""))
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, client, testService))
+
+		failing.Store(false)
+		checker.Check(t.Context())
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, client, testService))
+	})
+
+	t.Run("stays NOT_SERVING after shutdown", func(t *testing.T) {
+		checker.Shutdown()
+		checker.Check(t.Context())
+
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, client, ""))
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, client, testService))
+	})
+}
+
+func TestCheckerWithoutPing(t *testing.T) {
+	checker, client := setupChecker(t, nil)
+	checker.Check(t.Context())
+
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, status(t, client, testService))
+
+	_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "unknown.Service"})
+	assert.Error(t, err)
+}
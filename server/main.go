@@ -1,41 +1,230 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
 	"net"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 
+	"github.com/cndrsdrmn/go-grpc/internal/tlsconfig"
 	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/audit"
+	"github.com/cndrsdrmn/go-grpc/server/auth"
+	"github.com/cndrsdrmn/go-grpc/server/config"
+	"github.com/cndrsdrmn/go-grpc/server/health"
+	"github.com/cndrsdrmn/go-grpc/server/migrations"
 	"github.com/cndrsdrmn/go-grpc/server/users"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-func NewGRPCServer(db *gorm.DB) *grpc.Server {
-	repo := users.NewUserRepository(db)
+type ServerConfig struct {
+	Tokens        *auth.TokenManager
+	PublicMethods []string
+	// OpenSignup makes CreateUser callable without a token.
+	OpenSignup bool
+	// BootstrapAdmins are promoted at startup while no user is an admin;
+	// those without an account are created with BootstrapPassword if set.
+	BootstrapAdmins   []string
+	BootstrapPassword string
+	TLS               *tls.Config
+	// ConnectionTimeout bounds the connection handshake; zero keeps the
+	// grpc default.
+	ConnectionTimeout time.Duration
+	// Health reports the status of the registered services; nil always
+	// reports SERVING.
+	Health *health.Checker
+	// Reflection serves the gRPC reflection service.
+	Reflection bool
+}
+
+func NewGRPCServer(repo users.UserRepositoryInterface, auditRepo audit.RepositoryInterface, cfg ServerConfig) *grpc.Server {
 	srvs := users.NewUserService(repo)
+	if err := auth.BootstrapAdmins(context.Background(), repo, srvs, cfg.BootstrapAdmins, cfg.BootstrapPassword); err != nil {
+		log.Fatalf("Cannot bootstrap admins: %v", err)
+	}
+	authSrvs := auth.NewAuthService(repo, cfg.Tokens)
+	publicMethods := cfg.PublicMethods
+	if cfg.OpenSignup {
+		publicMethods = append(slices.Clone(publicMethods), protos.UserService_CreateUser_FullMethodName)
+	}
+	authn := auth.NewInterceptor(cfg.Tokens, publicMethods)
+	authz := auth.NewAuthorizer(auth.DefaultPolicy, repo.FindRoles)
+	recorder := audit.NewRecorder(auditRepo)
 
-	server := grpc.NewServer()
+	// The recorder runs first so that calls rejected by authentication or
+	// authorization are audited too, along with the caller when known.
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(recorder.Unary(), authn.Unary(), authz.Unary(), users.UnaryValidationInterceptor()),
+		grpc.ChainStreamInterceptor(recorder.Stream(), authn.Stream(), authz.Stream(), users.StreamValidationInterceptor()),
+	}
+	if cfg.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg.TLS)))
+	}
+	if cfg.ConnectionTimeout > 0 {
+		opts = append(opts, grpc.ConnectionTimeout(cfg.ConnectionTimeout))
+	}
+
+	server := grpc.NewServer(opts...)
 	protos.RegisterUserServiceServer(server, srvs)
+	protos.RegisterAuthServiceServer(server, authSrvs)
+	protos.RegisterAuditServiceServer(server, audit.NewAuditService(auditRepo))
+
+	checker := cfg.Health
+	if checker == nil {
+		checker = health.NewChecker(nil)
+	}
+	checker.Register(server)
+
+	if cfg.Reflection {
+		reflection.Register(server)
+	}
 	return server
 }
 
-func main() {
-	db, err := gorm.Open(sqlite.Open("database.sqlite"), &gorm.Config{})
+func signingKey(cfg config.Config) []byte {
+	if cfg.Auth.SigningKey != "" {
+		return []byte(cfg.Auth.SigningKey)
+	}
+
+	slog.Warn("auth.signing_key is not set, using a random key; tokens will not survive a restart")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalf("Cannot generate signing key: %v", err)
+	}
+	return key
+}
+
+func tlsConfig(cfg config.Config) *tls.Config {
+	if cfg.TLS.CertFile == "" {
+		slog.Info("tls.cert_file is not set, serving plaintext")
+		return nil
+	}
+
+	reloader, err := tlsconfig.NewReloader(tlsconfig.ServerOptions{
+		CertFile:     cfg.TLS.CertFile,
+		KeyFile:      cfg.TLS.KeyFile,
+		ClientCAFile: cfg.TLS.ClientCAFile,
+	})
+	if err != nil {
+		log.Fatalf("Cannot load TLS certificate: %v", err)
+	}
+	return reloader.Config()
+}
+
+func loadConfig(args []string) config.Config {
+	cfg, err := config.Load(args, os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	slog.SetLogLoggerLevel(cfg.Level())
+	return cfg
+}
+
+func openDatabase(cfg config.Config) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(cfg.DatabaseDSN), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("Cannot connect to database: %v", err)
 	}
-	db.AutoMigrate(&users.User{})
+	return db
+}
+
+// repositories opens the configured storage along with a ping for health
+// checks. The database must be fully migrated; in-memory storage starts
+// empty, is lost on exit and needs no ping.
+func repositories(cfg config.Config) (users.UserRepositoryInterface, audit.RepositoryInterface, health.Pinger) {
+	if cfg.Storage == config.StorageMemory {
+		slog.Warn("Using in-memory storage; all data is lost when the server stops")
+		return users.NewMemoryUserRepository(), audit.NewMemoryRepository(), nil
+	}
 
-	lis, err := net.Listen("tcp", ":50051")
+	db := openDatabase(cfg)
+	if err := migrations.NewMigrator(db).Check(); err != nil {
+		log.Fatalf("Refusing to serve: %v; run `server migrate up` first", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Cannot connect to database: %v", err)
+	}
+	return users.NewUserRepository(db), audit.NewRepository(db), sqlDB.PingContext
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	cfg := loadConfig(os.Args[1:])
+	if err := users.SetPasswordCost(cfg.BcryptCost); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	repo, auditRepo, ping := repositories(cfg)
+	checker := health.NewChecker(ping)
+
+	lis, err := net.Listen("tcp", cfg.ListenAddr)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	server := NewGRPCServer(db)
+	server := NewGRPCServer(repo, auditRepo, ServerConfig{
+		Tokens:            auth.NewTokenManager(signingKey(cfg), cfg.Auth.TokenTTL),
+		PublicMethods:     cfg.Auth.PublicMethods,
+		OpenSignup:        cfg.Auth.OpenSignup,
+		BootstrapAdmins:   cfg.Auth.BootstrapAdmins,
+		BootstrapPassword: cfg.Auth.BootstrapPassword,
+		TLS:               tlsConfig(cfg),
+		ConnectionTimeout: cfg.Timeouts.Connection,
+		Health:            checker,
+		Reflection:        cfg.Reflection,
+	})
+	go checker.Run(context.Background(), cfg.Timeouts.HealthCheck)
+
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		shutdown(server, checker, cfg.Timeouts.Shutdown)
+	}()
 
-	log.Println("Server running at :50051")
+	slog.Info("Server running", "addr", lis.Addr().String())
 	if err := server.Serve(lis); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
 	}
 }
+
+// shutdown reports NOT_SERVING, then waits up to timeout for in-flight calls
+// before closing the remaining connections.
+func shutdown(server *grpc.Server, checker *health.Checker, timeout time.Duration) {
+	slog.Info("Shutting down", "timeout", timeout)
+	checker.Shutdown()
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Warn("Shutdown timeout reached, closing remaining connections")
+		server.Stop()
+	}
+}
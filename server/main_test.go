@@ -2,16 +2,26 @@ package main
 
 import (
 	"context"
+	"io"
 	"log"
 	"net"
 	"os"
 	"testing"
 
 	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/audit"
+	"github.com/cndrsdrmn/go-grpc/server/auth"
+	"github.com/cndrsdrmn/go-grpc/server/migrations"
 	"github.com/cndrsdrmn/go-grpc/server/users"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"gorm.io/driver/sqlite"
@@ -21,17 +31,20 @@ import (
 var bufSize = 1024 * 1024
 var lis *bufconn.Listener
 var testDB *gorm.DB
+var testTokens = auth.NewTokenManager([]byte("test-signing-key"), auth.DefaultTokenTTL)
 
 func TestMain(m *testing.M) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("Cannot connect to database: %v", err)
 	}
-	db.AutoMigrate(&users.User{})
+	if _, err := migrations.NewMigrator(db).Up(); err != nil {
+		log.Fatalf("Cannot migrate database: %v", err)
+	}
 
 	lis = bufconn.Listen(bufSize)
 
-	server := NewGRPCServer(db)
+	server := NewGRPCServer(users.NewUserRepository(db), audit.NewRepository(db), ServerConfig{Tokens: testTokens, PublicMethods: auth.DefaultPublicMethods})
 	testDB = db
 
 	go func() {
@@ -55,6 +68,19 @@ func factoryUserCreate(user *users.User) error {
 	return testDB.Create(user).Error
 }
 
+func authContext(t *testing.T) context.Context {
+	assert.NoError(t, testDB.FirstOrCreate(&users.UserRole{UserID: 1, Role: users.RoleAdmin}).Error)
+
+	return tokenContext(t, 1, "tester@example.com")
+}
+
+func tokenContext(t *testing.T, id uint, email string) context.Context {
+	token, err := testTokens.Issue(id, email)
+	assert.NoError(t, err)
+
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
 func setupTestServer(t *testing.T) (*grpc.ClientConn, func()) {
 	conn, err := grpc.NewClient(
 		"passthrough:///bufnet",
@@ -72,23 +98,217 @@ func setupTestServer(t *testing.T) (*grpc.ClientConn, func()) {
 	return conn, cleanup
 }
 
+// startTestServer serves testDB with cfg instead of the shared test server.
+func startTestServer(t *testing.T, cfg ServerConfig) *grpc.ClientConn {
+	lis := bufconn.Listen(bufSize)
+	server := NewGRPCServer(users.NewUserRepository(testDB), audit.NewRepository(testDB), cfg)
+	go server.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+		teardownTest(t)
+	})
+	return conn
+}
+
 func teardownTest(t *testing.T) {
 	if err := testDB.Exec("DELETE FROM users").Error; err != nil {
 		t.Fatalf("failed to clear users table: %v", err)
 	}
 
+	if err := testDB.Exec("DELETE FROM user_roles").Error; err != nil {
+		t.Fatalf("failed to clear user_roles table: %v", err)
+	}
+
+	if err := testDB.Exec("DELETE FROM audit_events").Error; err != nil {
+		t.Fatalf("failed to clear audit_events table: %v", err)
+	}
+
 	if err := testDB.Exec("DELETE FROM sqlite_sequence WHERE name='users'").Error; err != nil {
 		t.Fatalf("failed to reset autoincrement sequence: %v", err)
 	}
 }
 
+func TestAuthentication(t *testing.T) {
+	conn, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	client := protos.NewUserServiceClient(conn)
+
+	t.Run("rejects a request without a token", func(t *testing.T) {
+		_, err := client.AllUsers(context.Background(), &emptypb.Empty{})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("rejects a request with an invalid token", func(t *testing.T) {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer invalid")
+		_, err := client.AllUsers(ctx, &emptypb.Empty{})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("rejects a stream without a token", func(t *testing.T) {
+		stream, err := client.StreamUsers(context.Background(), &protos.StreamUsersRequest{})
+		assert.NoError(t, err)
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("rejects sign-up without a token by default", func(t *testing.T) {
+		_, err := client.CreateUser(context.Background(), &protos.CreateUserRequest{
+			Name:     "John Doe",
+			Email:    "john@example.com",
+			Password: "secret",
+		})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("allows sign-up without a token when open", func(t *testing.T) {
+		conn := startTestServer(t, ServerConfig{Tokens: testTokens, PublicMethods: auth.DefaultPublicMethods, OpenSignup: true})
+
+		_, err := protos.NewUserServiceClient(conn).CreateUser(context.Background(), &protos.CreateUserRequest{
+			Name:     "John Doe",
+			Email:    "john@example.com",
+			Password: "secret",
+		})
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestHealth(t *testing.T) {
+	conn, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	client := healthpb.NewHealthClient(conn)
+
+	for _, service := range []string{"", protos.UserService_ServiceDesc.ServiceName, protos.AuthService_ServiceDesc.ServiceName} {
+		res, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+
+		assert.NoError(t, err, "service %q", service)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.GetStatus(), "service %q", service)
+	}
+}
+
+func listServices(ctx context.Context, conn *grpc.ClientConn) ([]string, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		return nil, err
+	}
+	res, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, service := range res.GetListServicesResponse().GetService() {
+		names = append(names, service.Name)
+	}
+	return names, nil
+}
+
+func TestReflection(t *testing.T) {
+	t.Run("is off by default", func(t *testing.T) {
+		conn, cleanup := setupTestServer(t)
+		defer cleanup()
+
+		_, err := listServices(context.Background(), conn)
+
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+
+	t.Run("lists the services without a token when enabled", func(t *testing.T) {
+		conn := startTestServer(t, ServerConfig{
+			Tokens:        testTokens,
+			PublicMethods: auth.DefaultPublicMethods,
+			Reflection:    true,
+		})
+
+		names, err := listServices(context.Background(), conn)
+
+		assert.NoError(t, err)
+		assert.Contains(t, names, protos.UserService_ServiceDesc.ServiceName)
+		assert.Contains(t, names, protos.AuditService_ServiceDesc.ServiceName)
+	})
+}
+
+func TestAuthorization(t *testing.T) {
+	conn, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	admin := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
+	member := &users.User{Name: "Jane Doe", Email: "jane@example.com", Password: "secret"}
+	factoryUserCreate(admin)
+	factoryUserCreate(member)
+
+	client := protos.NewUserServiceClient(conn)
+
+	adminCtx := authContext(t)
+	memberCtx := tokenContext(t, member.ID, member.Email)
+
+	t.Run("a user may update only themselves", func(t *testing.T) {
+		_, err := client.UpdateUser(memberCtx, &protos.UpdateUserRequest{Id: uint64(member.ID), Name: "Jane"})
+		assert.NoError(t, err)
+
+		_, err = client.UpdateUser(memberCtx, &protos.UpdateUserRequest{Id: uint64(admin.ID), Name: "Jane"})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("only admins can delete users", func(t *testing.T) {
+		_, err := client.DeleteUser(memberCtx, &protos.DeleteUserRequest{Id: uint64(admin.ID)})
+
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("granted roles take effect immediately", func(t *testing.T) {
+		_, err := client.AllUsers(memberCtx, &emptypb.Empty{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		res, err := client.GrantRole(adminCtx, &protos.RoleRequest{UserId: uint64(member.ID), Role: protos.Role_ROLE_MANAGER})
+		assert.NoError(t, err)
+		assert.Equal(t, []protos.Role{protos.Role_ROLE_MANAGER}, res.User.Roles)
+
+		_, err = client.AllUsers(memberCtx, &emptypb.Empty{})
+		assert.NoError(t, err)
+
+		_, err = client.RevokeRole(adminCtx, &protos.RoleRequest{UserId: uint64(member.ID), Role: protos.Role_ROLE_MANAGER})
+		assert.NoError(t, err)
+
+		_, err = client.AllUsers(memberCtx, &emptypb.Empty{})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("only admins can grant roles", func(t *testing.T) {
+		_, err := client.GrantRole(memberCtx, &protos.RoleRequest{UserId: uint64(member.ID), Role: protos.Role_ROLE_ADMIN})
+
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
 func TestCreateUser(t *testing.T) {
 	conn, cleanup := setupTestServer(t)
 	defer cleanup()
 
 	client := protos.NewUserServiceClient(conn)
 
-	ctx := context.Background()
+	ctx := authContext(t)
 	req := &protos.CreateUserRequest{
 		Name:     "John Doe",
 		Email:    "john@example.com",
@@ -108,9 +328,40 @@ func TestCreateUser(t *testing.T) {
 		_, err := client.CreateUser(ctx, req)
 
 		assert.Error(t, err, gorm.ErrDuplicatedKey)
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("failed create an invalid user", func(t *testing.T) {
+		_, err := client.CreateUser(ctx, &protos.CreateUserRequest{Name: "John Doe", Email: "invalid"})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
 	})
 }
 
+func TestBulkCreateUsers(t *testing.T) {
+	conn, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	client := protos.NewUserServiceClient(conn)
+
+	stream, err := client.BulkCreateUsers(authContext(t))
+	assert.NoError(t, err)
+
+	for _, req := range []*protos.CreateUserRequest{
+		{Name: "Charlie", Email: "charlie@example.com", Password: "secret"},
+		{Name: "Charlie", Email: "charlie@example.com", Password: "secret"},
+		{Name: "David", Email: "david@example.com", Password: "password"},
+	} {
+		assert.NoError(t, stream.Send(req))
+	}
+
+	res, err := stream.CloseAndRecv()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), res.CreatedCount)
+	assert.Equal(t, int32(1), res.FailedCount)
+	assert.Equal(t, users.ReasonEmailAlreadyExists, res.Results[1].ErrorReason)
+}
+
 func TestGetUser(t *testing.T) {
 	conn, cleanup := setupTestServer(t)
 	defer cleanup()
@@ -120,7 +371,7 @@ func TestGetUser(t *testing.T) {
 
 	client := protos.NewUserServiceClient(conn)
 
-	ctx := context.Background()
+	ctx := authContext(t)
 
 	t.Run("find an existing user", func(t *testing.T) {
 		res, err := client.GetUser(ctx, &protos.GetUserRequest{Id: uint64(user.ID)})
@@ -135,6 +386,32 @@ func TestGetUser(t *testing.T) {
 		_, err := client.GetUser(ctx, &protos.GetUserRequest{Id: 999})
 
 		assert.Error(t, err, gorm.ErrRecordNotFound)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestLogin(t *testing.T) {
+	conn, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	factoryUserCreate(&users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"})
+
+	client := protos.NewAuthServiceClient(conn)
+
+	ctx := authContext(t)
+
+	t.Run("valid credentials", func(t *testing.T) {
+		res, err := client.Login(ctx, &protos.LoginRequest{Email: "john@example.com", Password: "secret"})
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, res.AccessToken)
+		assert.Equal(t, "Bearer", res.TokenType)
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		_, err := client.Login(ctx, &protos.LoginRequest{Email: "john@example.com", Password: "wrong"})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
 	})
 }
 
@@ -144,7 +421,7 @@ func TestAllUsers(t *testing.T) {
 
 	client := protos.NewUserServiceClient(conn)
 
-	ctx := context.Background()
+	ctx := authContext(t)
 
 	factoryUserCreate(&users.User{Name: "Charlie", Email: "charlie@example.com", Password: "secret"})
 	factoryUserCreate(&users.User{Name: "David", Email: "david@example.com", Password: "password"})
@@ -154,13 +431,76 @@ func TestAllUsers(t *testing.T) {
 	assert.Len(t, res.Users, 2)
 }
 
+func TestListUsers(t *testing.T) {
+	conn, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	client := protos.NewUserServiceClient(conn)
+
+	ctx := authContext(t)
+
+	factoryUserCreate(&users.User{Name: "Charlie", Email: "charlie@example.com", Password: "secret"})
+	factoryUserCreate(&users.User{Name: "David", Email: "david@example.com", Password: "password"})
+
+	res, err := client.ListUsers(ctx, &protos.ListUsersRequest{PageSize: 1})
+	assert.NoError(t, err)
+	assert.Len(t, res.Users, 1)
+	assert.NotEmpty(t, res.NextPageToken)
+
+	res, err = client.ListUsers(ctx, &protos.ListUsersRequest{PageSize: 1, PageToken: res.NextPageToken})
+	assert.NoError(t, err)
+	assert.Len(t, res.Users, 1)
+	assert.Equal(t, "David", res.Users[0].Name)
+	assert.Empty(t, res.NextPageToken)
+
+	_, err = client.ListUsers(ctx, &protos.ListUsersRequest{PageSize: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestStreamUsers(t *testing.T) {
+	conn, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	client := protos.NewUserServiceClient(conn)
+
+	ctx := authContext(t)
+
+	factoryUserCreate(&users.User{Name: "Charlie", Email: "charlie@example.com", Password: "secret"})
+	factoryUserCreate(&users.User{Name: "David", Email: "david@example.com", Password: "password"})
+
+	t.Run("receives every user", func(t *testing.T) {
+		stream, err := client.StreamUsers(ctx, &protos.StreamUsersRequest{BatchSize: 1})
+		assert.NoError(t, err)
+
+		var names []string
+		for {
+			user, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			names = append(names, user.Name)
+		}
+
+		assert.Equal(t, []string{"Charlie", "David"}, names)
+	})
+
+	t.Run("rejects an invalid batch size", func(t *testing.T) {
+		stream, err := client.StreamUsers(ctx, &protos.StreamUsersRequest{BatchSize: 5000})
+		assert.NoError(t, err)
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
 func TestUpdateUser(t *testing.T) {
 	conn, cleanup := setupTestServer(t)
 	defer cleanup()
 
 	client := protos.NewUserServiceClient(conn)
 
-	ctx := context.Background()
+	ctx := authContext(t)
 
 	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
 	factoryUserCreate(user)
@@ -172,13 +512,57 @@ func TestUpdateUser(t *testing.T) {
 	assert.Equal(t, "john@example.com", res.User.Email)
 }
 
+func TestWatchUsers(t *testing.T) {
+	conn, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	client := protos.NewUserServiceClient(conn)
+
+	ctx, cancel := context.WithCancel(authContext(t))
+	defer cancel()
+
+	stream, err := client.WatchUsers(ctx, &protos.WatchUsersRequest{})
+	assert.NoError(t, err)
+
+	// Wait for the watch to be registered before mutating.
+	_, err = stream.Header()
+	assert.NoError(t, err)
+
+	created, err := client.CreateUser(ctx, &protos.CreateUserRequest{Name: "John Doe", Email: "john@example.com", Password: "secret"})
+	assert.NoError(t, err)
+
+	_, err = client.UpdateUser(ctx, &protos.UpdateUserRequest{Id: created.User.Id, Name: "Charlie"})
+	assert.NoError(t, err)
+
+	first, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, protos.UserEvent_CREATED, first.Type)
+	assert.Equal(t, "John Doe", first.User.Name)
+
+	second, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, protos.UserEvent_UPDATED, second.Type)
+	assert.Equal(t, "Charlie", second.User.Name)
+	assert.Equal(t, first.Revision+1, second.Revision)
+
+	t.Run("resumes after a reconnect", func(t *testing.T) {
+		resumed, err := client.WatchUsers(ctx, &protos.WatchUsersRequest{SinceRevision: first.Revision})
+		assert.NoError(t, err)
+
+		event, err := resumed.Recv()
+		assert.NoError(t, err)
+		assert.Equal(t, second.Revision, event.Revision)
+	})
+}
+
 func TestDeleteUser(t *testing.T) {
 	conn, cleanup := setupTestServer(t)
 	defer cleanup()
 
 	client := protos.NewUserServiceClient(conn)
 
-	ctx := context.Background()
+	factoryUserCreate(&users.User{Name: "Tester", Email: "tester@example.com", Password: "secret"})
+	ctx := authContext(t)
 
 	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
 	factoryUserCreate(user)
@@ -187,4 +571,94 @@ func TestDeleteUser(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.True(t, res.Success)
+
+	t.Run("restore the deleted user", func(t *testing.T) {
+		res, err := client.RestoreUser(ctx, &protos.RestoreUserRequest{Id: uint64(user.ID)})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "john@example.com", res.User.Email)
+	})
+
+	t.Run("purge the user", func(t *testing.T) {
+		res, err := client.PurgeUser(ctx, &protos.PurgeUserRequest{Id: uint64(user.ID)})
+
+		assert.NoError(t, err)
+		assert.True(t, res.Success)
+
+		_, err = client.RestoreUser(ctx, &protos.RestoreUserRequest{Id: uint64(user.ID)})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestListAuditEvents(t *testing.T) {
+	conn, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	client := protos.NewUserServiceClient(conn)
+	auditClient := protos.NewAuditServiceClient(conn)
+
+	factoryUserCreate(&users.User{Name: "Tester", Email: "tester@example.com", Password: "secret"})
+	ctx := metadata.AppendToOutgoingContext(authContext(t), audit.RequestIDHeader, "req-1")
+
+	var header metadata.MD
+	created, err := client.CreateUser(ctx, &protos.CreateUserRequest{Name: "John Doe", Email: "john@example.com", Password: "secret"}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"req-1"}, header.Get(audit.RequestIDHeader))
+
+	password := "changed"
+	_, err = client.UpdateUser(authContext(t), &protos.UpdateUserRequest{Id: created.User.Id, Password: &password})
+	assert.NoError(t, err)
+
+	_, err = client.DeleteUser(tokenContext(t, uint(created.User.Id), "john@example.com"), &protos.DeleteUserRequest{Id: created.User.Id})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	invalid := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer invalid")
+	_, err = client.DeleteUser(invalid, &protos.DeleteUserRequest{Id: created.User.Id})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.GetUser(authContext(t), &protos.GetUserRequest{Id: created.User.Id})
+	assert.NoError(t, err)
+
+	t.Run("lists mutations newest first", func(t *testing.T) {
+		res, err := auditClient.ListAuditEvents(authContext(t), &protos.ListAuditEventsRequest{TargetUserId: created.User.Id})
+		assert.NoError(t, err)
+
+		if assert.Len(t, res.Events, 4) {
+			unauthenticated, denied, updated, create := res.Events[0], res.Events[1], res.Events[2], res.Events[3]
+
+			assert.Equal(t, protos.UserService_DeleteUser_FullMethodName, unauthenticated.Method)
+			assert.Zero(t, unauthenticated.ActorId)
+			assert.Equal(t, "UNAUTHENTICATED", unauthenticated.Outcome)
+
+			assert.Equal(t, protos.UserService_DeleteUser_FullMethodName, denied.Method)
+			assert.Equal(t, created.User.Id, denied.ActorId)
+			assert.Equal(t, "PERMISSION_DENIED", denied.Outcome)
+
+			assert.Equal(t, protos.UserService_UpdateUser_FullMethodName, updated.Method)
+			assert.Equal(t, []string{"password"}, updated.ChangedFields)
+			assert.Equal(t, uint64(1), updated.ActorId)
+			assert.NotEmpty(t, updated.RequestId)
+
+			assert.Equal(t, "req-1", create.RequestId)
+			assert.Equal(t, "tester@example.com", create.ActorEmail)
+			assert.Equal(t, audit.OutcomeOK, create.Outcome)
+		}
+	})
+
+	t.Run("pages through events", func(t *testing.T) {
+		first, err := auditClient.ListAuditEvents(authContext(t), &protos.ListAuditEventsRequest{PageSize: 2})
+		assert.NoError(t, err)
+		assert.Len(t, first.Events, 2)
+
+		rest, err := auditClient.ListAuditEvents(authContext(t), &protos.ListAuditEventsRequest{PageSize: 2, PageToken: first.NextPageToken})
+		assert.NoError(t, err)
+		assert.Len(t, rest.Events, 2)
+		assert.Empty(t, rest.NextPageToken)
+	})
+
+	t.Run("only admins can read the audit log", func(t *testing.T) {
+		_, err := auditClient.ListAuditEvents(tokenContext(t, uint(created.User.Id), "john@example.com"), &protos.ListAuditEventsRequest{})
+
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
 }
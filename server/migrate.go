@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cndrsdrmn/go-grpc/server/config"
+	"github.com/cndrsdrmn/go-grpc/server/migrations"
+)
+
+const migrateUsage = "usage: server migrate up|down|status [flags]"
+
+// runMigrate handles `server migrate`. It takes the same flags as the server
+// so it always targets the database the server would open.
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	cfg := loadConfig(args[1:])
+	if cfg.Storage == config.StorageMemory {
+		log.Fatal("In-memory storage has no schema to migrate")
+	}
+	migrator := migrations.NewMigrator(openDatabase(cfg))
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+	case "down":
+		migration, err := migrator.Down()
+		if errors.Is(err, migrations.ErrNothingToRevert) {
+			fmt.Println("Nothing to revert")
+			return
+		}
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("Cannot read migration status: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		w.Flush()
+		if err := migrator.Check(); errors.Is(err, migrations.ErrUnknownMigration) {
+			fmt.Println(err)
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
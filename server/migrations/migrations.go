@@ -0,0 +1,249 @@
+package migrations
+
+import (
+	"cmp"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+const createTable = "CREATE TABLE IF NOT EXISTS `schema_migrations` (" +
+	"`version` integer PRIMARY KEY, `name` text NOT NULL, `applied_at` datetime NOT NULL)"
+
+var (
+	ErrNothingToRevert   = errors.New("no migration has been applied")
+	ErrPendingMigrations = errors.New("database has pending migrations")
+	ErrUnknownMigration  = errors.New("database has migrations this binary does not know")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	// AppliedAt is nil while the migration is pending.
+	AppliedAt *time.Time
+}
+
+type appliedMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type MigratorInterface interface {
+	Up() ([]Migration, error)
+	Down() (*Migration, error)
+	Status() ([]Status, error)
+	Check() error
+}
+
+type migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction, and returns the ones it applied.
+func (m *migrator) Up() ([]Migration, error) {
+	if err := m.db.Exec(createTable).Error; err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&appliedMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down reverts the most recently applied migration.
+func (m *migrator) Down() (*Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&appliedMigration{Version: migration.Version}).Error
+		})
+		if err != nil {
+			return nil, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		return &migration, nil
+	}
+
+	return nil, ErrNothingToRevert
+}
+
+func (m *migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Check fails unless the database is at exactly the latest known version,
+// so a server never runs against a schema it was not built for.
+func (m *migrator) Check() error {
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			delete(applied, migration.Version)
+		} else {
+			pending++
+		}
+	}
+
+	if len(applied) > 0 {
+		return ErrUnknownMigration
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d of %d not applied", ErrPendingMigrations, pending, len(m.migrations))
+	}
+	return nil
+}
+
+func (m *migrator) applied() (map[uint]appliedMigration, error) {
+	rows := map[uint]appliedMigration{}
+	if !m.db.Migrator().HasTable(&appliedMigration{}) {
+		return rows, nil
+	}
+
+	var list []appliedMigration
+	if err := m.db.Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range list {
+		rows[row.Version] = row
+	}
+	return rows, nil
+}
+
+// Load reads NNNN_name.up.sql and NNNN_name.down.sql pairs from fsys.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+
+		version, _ := strconv.ParseUint(match[1], 10, 32)
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %04d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return migrations, nil
+}
+
+// NewMigrator runs the migrations embedded in the binary.
+func NewMigrator(db *gorm.DB) MigratorInterface {
+	sub, _ := fs.Sub(embedded, "sql")
+	migrations, err := Load(sub)
+	if err != nil {
+		// The embedded files are fixed at build time and covered by tests.
+		panic(err)
+	}
+	return NewMigratorFrom(db, migrations)
+}
+
+func NewMigratorFrom(db *gorm.DB, migrations []Migration) MigratorInterface {
+	return &migrator{db: db, migrations: migrations}
+}
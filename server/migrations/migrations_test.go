@@ -0,0 +1,157 @@
+package migrations_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/cndrsdrmn/go-grpc/internal/testutil"
+	"github.com/cndrsdrmn/go-grpc/server/audit"
+	"github.com/cndrsdrmn/go-grpc/server/migrations"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMigratorUpAndDown(t *testing.T) {
+	db := testutil.OpenMemoryDB(t)
+	migrator := migrations.NewMigrator(db)
+
+	assert.ErrorIs(t, migrator.Check(), migrations.ErrPendingMigrations)
+
+	applied, err := migrator.Up()
+	require.NoError(t, err)
+	assert.NotEmpty(t, applied)
+	assert.NoError(t, migrator.Check())
+
+	again, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Empty(t, again)
+
+	reverted, err := migrator.Down()
+	require.NoError(t, err)
+	assert.Equal(t, applied[len(applied)-1].Version, reverted.Version)
+	assert.ErrorIs(t, migrator.Check(), migrations.ErrPendingMigrations)
+
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.Len(t, statuses, len(applied))
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[len(statuses)-1].AppliedAt)
+
+	for range applied[1:] {
+		_, err := migrator.Down()
+		require.NoError(t, err)
+	}
+	_, err = migrator.Down()
+	assert.ErrorIs(t, err, migrations.ErrNothingToRevert)
+	assert.False(t, db.Migrator().HasTable("users"))
+}
+
+// The models are still read and written through gorm, so every column and
+// index gorm expects must exist after migrating.
+func TestMigrationsMatchModels(t *testing.T) {
+	db := testutil.OpenMemoryDB(t)
+	_, err := migrations.NewMigrator(db).Up()
+	require.NoError(t, err)
+
+	for _, model := range []any{&users.User{}, &users.UserRole{}, &audit.Event{}} {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(model))
+
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" {
+				assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%s.%s", stmt.Schema.Table, field.DBName)
+			}
+		}
+		for _, index := range stmt.Schema.ParseIndexes() {
+			assert.True(t, db.Migrator().HasIndex(model, index.Name), "%s index %s", stmt.Schema.Table, index.Name)
+		}
+	}
+}
+
+// baselineUser is the users model of the first release, which created its
+// table with AutoMigrate instead of migrations.
+type baselineUser struct {
+	gorm.Model
+	Name     string `gorm:"not null"`
+	Email    string `gorm:"uniqueIndex;not null"`
+	Password string
+}
+
+func (baselineUser) TableName() string {
+	return "users"
+}
+
+func TestMigratorAdoptsAnAutoMigratedDatabase(t *testing.T) {
+	db := testutil.OpenMemoryDB(t)
+	require.NoError(t, db.AutoMigrate(&baselineUser{}))
+	existing := &baselineUser{Name: "John", Email: "john@example.com", Password: "hashed"}
+	require.NoError(t, db.Create(existing).Error)
+
+	_, err := migrations.NewMigrator(db).Up()
+	require.NoError(t, err)
+
+	repo := users.NewUserRepository(db)
+	update := &users.User{Name: "John Doe"}
+	require.NoError(t, repo.UpdateUser(t.Context(), existing.ID, update, []string{"name"}))
+
+	found, err := repo.FindUser(t.Context(), existing.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "John Doe", found.Name)
+	assert.Equal(t, "john@example.com", found.Email)
+	assert.Equal(t, uint(2), found.Version)
+}
+
+func TestMigratorRejectsUnknownVersions(t *testing.T) {
+	db := testutil.OpenMemoryDB(t)
+	_, err := migrations.NewMigrator(db).Up()
+	require.NoError(t, err)
+
+	older := migrations.NewMigratorFrom(db, nil)
+	assert.ErrorIs(t, older.Check(), migrations.ErrUnknownMigration)
+}
+
+func TestMigratorRollsBackAFailedMigration(t *testing.T) {
+	db := testutil.OpenMemoryDB(t)
+	migrator := migrations.NewMigratorFrom(db, []migrations.Migration{
+		{Version: 1, Name: "create_things", Up: "CREATE TABLE things (id integer)", Down: "DROP TABLE things"},
+		{Version: 2, Name: "broken", Up: "CREATE TABLE others (id integer); NOT SQL", Down: "DROP TABLE others"},
+	})
+
+	applied, err := migrator.Up()
+
+	assert.ErrorContains(t, err, "migration 0002_broken")
+	assert.Len(t, applied, 1)
+	assert.True(t, db.Migrator().HasTable("things"))
+	assert.False(t, db.Migrator().HasTable("others"))
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("orders migrations by version", func(t *testing.T) {
+		loaded, err := migrations.Load(fstest.MapFS{
+			"0002_b.up.sql":   {Data: []byte("B")},
+			"0002_b.down.sql": {Data: []byte("-B")},
+			"0001_a.up.sql":   {Data: []byte("A")},
+			"0001_a.down.sql": {Data: []byte("-A")},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []migrations.Migration{
+			{Version: 1, Name: "a", Up: "A", Down: "-A"},
+			{Version: 2, Name: "b", Up: "B", Down: "-B"},
+		}, loaded)
+	})
+
+	t.Run("requires a down file", func(t *testing.T) {
+		_, err := migrations.Load(fstest.MapFS{"0001_a.up.sql": {Data: []byte("A")}})
+
+		assert.ErrorContains(t, err, "needs both an up and a down file")
+	})
+
+	t.Run("rejects unexpected files", func(t *testing.T) {
+		_, err := migrations.Load(fstest.MapFS{"notes.txt": {}})
+
+		assert.ErrorContains(t, err, "unexpected migration file notes.txt")
+	})
+}
@@ -0,0 +1,14 @@
+-- IF NOT EXISTS lets databases created by the old AutoMigrate call adopt
+-- this history without losing data, so this table must stay exactly what that
+-- call created; newer columns belong in later migrations.
+CREATE TABLE IF NOT EXISTS `users` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `name` text NOT NULL,
+    `email` text NOT NULL,
+    `password` text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_email` ON `users`(`email`);
+CREATE INDEX IF NOT EXISTS `idx_users_deleted_at` ON `users`(`deleted_at`);
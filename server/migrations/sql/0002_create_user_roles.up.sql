@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS `user_roles` (
+    `user_id` integer,
+    `role` text,
+    PRIMARY KEY (`user_id`, `role`),
+    CONSTRAINT `fk_users_roles` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
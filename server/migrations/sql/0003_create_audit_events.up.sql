@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS `audit_events` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `actor_id` integer,
+    `actor_email` text,
+    `method` text NOT NULL,
+    `target_user_id` integer,
+    `changed_fields` text,
+    `request_id` text,
+    `outcome` text NOT NULL,
+    `prev_hash` text,
+    `hash` text
+);
+CREATE INDEX IF NOT EXISTS `idx_audit_events_request_id` ON `audit_events`(`request_id`);
+CREATE INDEX IF NOT EXISTS `idx_audit_events_target_user_id` ON `audit_events`(`target_user_id`);
+CREATE INDEX IF NOT EXISTS `idx_audit_events_actor_id` ON `audit_events`(`actor_id`);
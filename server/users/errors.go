@@ -0,0 +1,155 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"gorm.io/gorm"
+)
+
+const ErrorDomain = "users.go-grpc"
+
+const (
+	ReasonUserNotFound        = "USER_NOT_FOUND"
+	ReasonEmailAlreadyExists  = "EMAIL_ALREADY_EXISTS"
+	ReasonUserIDAlreadyExists = "USER_ID_ALREADY_EXISTS"
+	ReasonNoFieldsToUpdate    = "NO_FIELDS_TO_UPDATE"
+	ReasonPasswordTooLong     = "PASSWORD_TOO_LONG"
+	ReasonInvalidPageToken    = "INVALID_PAGE_TOKEN"
+	ReasonInvalidFilter       = "INVALID_FILTER"
+	ReasonInvalidOrderBy      = "INVALID_ORDER_BY"
+	ReasonRevisionUnavailable = "REVISION_UNAVAILABLE"
+	ReasonWatcherTooSlow      = "WATCHER_TOO_SLOW"
+	ReasonInvalidRole         = "INVALID_ROLE"
+	ReasonUserNotDeleted      = "USER_NOT_DELETED"
+	ReasonVersionConflict     = "VERSION_CONFLICT"
+	ReasonInvalidUpdateMask   = "INVALID_UPDATE_MASK"
+	ReasonInvalidArgument     = "INVALID_ARGUMENT"
+)
+
+var (
+	ErrUserNotFound        = errors.New("user not found")
+	ErrEmailAlreadyExists  = errors.New("email already exists")
+	ErrUserIDAlreadyExists = errors.New("user id already exists")
+	ErrNoFieldsToUpdate    = errors.New("no fields provided to update")
+	ErrPasswordTooLong     = errors.New("password exceeds 72 bytes")
+	ErrInvalidPageToken    = errors.New("invalid page token")
+	ErrInvalidFilter       = errors.New("invalid filter")
+	ErrInvalidOrderBy      = errors.New("invalid order_by")
+	ErrRevisionUnavailable = errors.New("revision is no longer available, list users and watch from revision 0")
+	ErrWatcherTooSlow      = errors.New("watcher fell behind, resume from the last received revision")
+	ErrInvalidRole         = errors.New("invalid role")
+	ErrUserNotDeleted      = errors.New("user is not deleted")
+	ErrVersionConflict     = errors.New("user was modified concurrently, fetch the latest version and retry")
+	ErrInvalidUpdateMask   = errors.New("invalid update mask")
+)
+
+func translateRepositoryError(db *gorm.DB, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// A query interrupted by cancellation fails with a driver error; report
+	// the cancellation itself so callers see Canceled or DeadlineExceeded.
+	if ctxErr := db.Statement.Context.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	raw := err
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrUserNotFound
+	// Every unique violation translates to ErrDuplicatedKey, so the taken
+	// primary key is told apart by the column the driver names.
+	case errors.Is(err, gorm.ErrDuplicatedKey) && strings.Contains(raw.Error(), "users.id"):
+		return ErrUserIDAlreadyExists
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrEmailAlreadyExists
+	}
+
+	return err
+}
+
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr.GRPCStatus().Err()
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		return domainError(codes.NotFound, ReasonUserNotFound, err)
+	case errors.Is(err, ErrEmailAlreadyExists):
+		return domainError(codes.AlreadyExists, ReasonEmailAlreadyExists, err)
+	case errors.Is(err, ErrUserIDAlreadyExists):
+		return domainError(codes.AlreadyExists, ReasonUserIDAlreadyExists, err)
+	case errors.Is(err, ErrNoFieldsToUpdate):
+		return domainError(codes.InvalidArgument, ReasonNoFieldsToUpdate, err)
+	case errors.Is(err, ErrPasswordTooLong):
+		return domainError(codes.InvalidArgument, ReasonPasswordTooLong, err)
+	case errors.Is(err, ErrInvalidPageToken):
+		return domainError(codes.InvalidArgument, ReasonInvalidPageToken, err)
+	case errors.Is(err, ErrInvalidFilter):
+		return domainError(codes.InvalidArgument, ReasonInvalidFilter, err)
+	case errors.Is(err, ErrInvalidOrderBy):
+		return domainError(codes.InvalidArgument, ReasonInvalidOrderBy, err)
+	case errors.Is(err, ErrRevisionUnavailable):
+		return domainError(codes.OutOfRange, ReasonRevisionUnavailable, err)
+	case errors.Is(err, ErrWatcherTooSlow):
+		return domainError(codes.ResourceExhausted, ReasonWatcherTooSlow, err)
+	case errors.Is(err, ErrInvalidRole):
+		return domainError(codes.InvalidArgument, ReasonInvalidRole, err)
+	case errors.Is(err, ErrUserNotDeleted):
+		return domainError(codes.FailedPrecondition, ReasonUserNotDeleted, err)
+	case errors.Is(err, ErrVersionConflict):
+		return domainError(codes.Aborted, ReasonVersionConflict, err)
+	case errors.Is(err, ErrInvalidUpdateMask):
+		return domainError(codes.InvalidArgument, ReasonInvalidUpdateMask, err)
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+func errorReason(err error) (string, string) {
+	st := status.Convert(toStatusError(err))
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason, st.Message()
+		}
+	}
+	return st.Code().String(), st.Message()
+}
+
+func domainError(code codes.Code, reason string, err error) error {
+	info := &errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain}
+	return withDetails(status.New(code, err.Error()), info).Err()
+}
+
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) *status.Status {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
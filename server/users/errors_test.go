@@ -0,0 +1,69 @@
+package users_test
+
+import (
+	"context"
+	"testing"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func errorInfo(err error) *errdetails.ErrorInfo {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	return nil
+}
+
+// takenIDRepository fails every create the way a conflicting primary key does.
+type takenIDRepository struct {
+	users.UserRepositoryInterface
+}
+
+func (takenIDRepository) CreateUser(context.Context, *users.User) error {
+	return users.ErrUserIDAlreadyExists
+}
+
+func TestErrorDetails(t *testing.T) {
+	srvs := setupUserServiceTest(t)
+	ctx := context.Background()
+	defer teardownTest(t)
+
+	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
+	factoryUserCreate(user)
+
+	t.Run("not found carries the user not found reason", func(t *testing.T) {
+		_, err := srvs.GetUser(ctx, &protos.GetUserRequest{Id: 999})
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		if info := errorInfo(err); assert.NotNil(t, info) {
+			assert.Equal(t, users.ReasonUserNotFound, info.Reason)
+			assert.Equal(t, users.ErrorDomain, info.Domain)
+		}
+	})
+
+	t.Run("duplicate email carries the email already exists reason", func(t *testing.T) {
+		_, err := srvs.CreateUser(ctx, &protos.CreateUserRequest{Name: "John Doe", Email: "john@example.com", Password: "secret"})
+
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		if info := errorInfo(err); assert.NotNil(t, info) {
+			assert.Equal(t, users.ReasonEmailAlreadyExists, info.Reason)
+		}
+	})
+
+	t.Run("taken user id carries its own reason", func(t *testing.T) {
+		srvs := users.NewUserService(takenIDRepository{})
+		_, err := srvs.CreateUser(ctx, &protos.CreateUserRequest{Name: "Jane Doe", Email: "jane@example.com", Password: "secret"})
+
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+		if info := errorInfo(err); assert.NotNil(t, info) {
+			assert.Equal(t, users.ReasonUserIDAlreadyExists, info.Reason)
+		}
+	})
+}
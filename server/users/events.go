@@ -0,0 +1,138 @@
+package users
+
+import (
+	"sync"
+
+	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+)
+
+const (
+	defaultEventHistory = 1024
+	subscriberBuffer    = 64
+)
+
+type EventBroker struct {
+	mu          sync.Mutex
+	revision    uint64
+	history     []*pb.UserEvent
+	historySize int
+	subscribers map[chan *pb.UserEvent]struct{}
+}
+
+func NewEventBroker(historySize int) *EventBroker {
+	return &EventBroker{
+		historySize: historySize,
+		subscribers: make(map[chan *pb.UserEvent]struct{}),
+	}
+}
+
+func (b *EventBroker) Revision() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.revision
+}
+
+func (b *EventBroker) Publish(typ pb.UserEvent_Type, user *pb.User) *pb.UserEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.revision++
+	event := &pb.UserEvent{Type: typ, User: user, Revision: b.revision}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return event
+}
+
+// Subscribe replays the retained events after since and then delivers new
+// ones on the returned channel. A since of zero only delivers new events.
+// The channel is closed if the subscriber falls too far behind.
+func (b *EventBroker) Subscribe(since uint64) ([]*pb.UserEvent, <-chan *pb.UserEvent, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []*pb.UserEvent
+	if since != 0 {
+		if since > b.revision || since < b.revision-uint64(len(b.history)) {
+			return nil, nil, nil, ErrRevisionUnavailable
+		}
+		backlog = append(backlog, b.history[len(b.history)-int(b.revision-since):]...)
+	}
+
+	ch := make(chan *pb.UserEvent, subscriberBuffer)
+	b.subscribers[ch] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return backlog, ch, cancel, nil
+}
+
+// userLocks serializes the changes to each user together with the publishing
+// of their events, so that revisions follow the order of the commits.
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[uint]*userLock
+	// creating is read-held by creates from the insert until their events are
+	// published, since a new user's id is not known before the insert.
+	creating sync.RWMutex
+}
+
+type userLock struct {
+	sync.Mutex
+	holders int
+}
+
+// create holds back every lock until the caller has published the users it
+// created, so that no change to a new user is announced before the user.
+func (l *userLocks) create() (unlock func()) {
+	l.creating.RLock()
+	return l.creating.RUnlock
+}
+
+func (l *userLocks) lock(id uint) (unlock func()) {
+	// Wait for the creates in flight, which may include this user's.
+	l.creating.Lock()
+	l.creating.Unlock()
+
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[uint]*userLock)
+	}
+	lock, ok := l.locks[id]
+	if !ok {
+		lock = &userLock{}
+		l.locks[id] = lock
+	}
+	lock.holders++
+	l.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if lock.holders--; lock.holders == 0 {
+			delete(l.locks, id)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package users_test
+
+import (
+	"testing"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBrokerPublish(t *testing.T) {
+	broker := users.NewEventBroker(10)
+
+	_, events, cancel, err := broker.Subscribe(0)
+	assert.NoError(t, err)
+	defer cancel()
+
+	first := broker.Publish(protos.UserEvent_CREATED, &protos.User{Id: 1})
+	second := broker.Publish(protos.UserEvent_UPDATED, &protos.User{Id: 1})
+
+	assert.Equal(t, uint64(1), first.Revision)
+	assert.Equal(t, uint64(2), second.Revision)
+	assert.Equal(t, uint64(2), broker.Revision())
+	assert.Equal(t, first, <-events)
+	assert.Equal(t, second, <-events)
+}
+
+func TestEventBrokerResume(t *testing.T) {
+	broker := users.NewEventBroker(3)
+	for i := 0; i < 5; i++ {
+		broker.Publish(protos.UserEvent_CREATED, &protos.User{Id: uint64(i + 1)})
+	}
+
+	t.Run("replays retained events after the revision", func(t *testing.T) {
+		backlog, _, cancel, err := broker.Subscribe(3)
+		assert.NoError(t, err)
+		defer cancel()
+
+		assert.Len(t, backlog, 2)
+		assert.Equal(t, uint64(4), backlog[0].Revision)
+		assert.Equal(t, uint64(5), backlog[1].Revision)
+	})
+
+	t.Run("resumes at the oldest retained revision", func(t *testing.T) {
+		backlog, _, cancel, err := broker.Subscribe(2)
+		assert.NoError(t, err)
+		defer cancel()
+
+		assert.Len(t, backlog, 3)
+	})
+
+	t.Run("resumes at the current revision", func(t *testing.T) {
+		backlog, _, cancel, err := broker.Subscribe(5)
+		assert.NoError(t, err)
+		defer cancel()
+
+		assert.Empty(t, backlog)
+	})
+
+	t.Run("rejects compacted revisions", func(t *testing.T) {
+		_, _, _, err := broker.Subscribe(1)
+
+		assert.ErrorIs(t, err, users.ErrRevisionUnavailable)
+	})
+
+	t.Run("rejects future revisions", func(t *testing.T) {
+		_, _, _, err := broker.Subscribe(6)
+
+		assert.ErrorIs(t, err, users.ErrRevisionUnavailable)
+	})
+}
+
+func TestEventBrokerSlowSubscriber(t *testing.T) {
+	broker := users.NewEventBroker(1000)
+
+	_, events, cancel, err := broker.Subscribe(0)
+	assert.NoError(t, err)
+	defer cancel()
+
+	for i := 0; i < 100; i++ {
+		broker.Publish(protos.UserEvent_CREATED, &protos.User{Id: uint64(i + 1)})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Less(t, received, 100)
+}
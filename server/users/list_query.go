@@ -0,0 +1,231 @@
+package users
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cndrsdrmn/go-grpc/server/filter"
+	"gorm.io/gorm/clause"
+)
+
+type fieldKind int
+
+const (
+	stringField fieldKind = iota
+	timeField
+	idField
+)
+
+type listField struct {
+	column     string
+	kind       fieldKind
+	filterable bool
+}
+
+var listFields = map[string]listField{
+	"id":         {column: "id", kind: idField},
+	"name":       {column: "name", kind: stringField, filterable: true},
+	"email":      {column: "email", kind: stringField, filterable: true},
+	"created_at": {column: "created_at", kind: timeField, filterable: true},
+}
+
+type OrderField struct {
+	Field string
+	Desc  bool
+}
+
+type ListQuery struct {
+	Filter      filter.Expr
+	OrderBy     []OrderField
+	After       *User
+	Limit       int
+	ShowDeleted bool
+}
+
+func ParseOrderBy(s string) ([]OrderField, error) {
+	var order []OrderField
+	seen := make(map[string]bool)
+
+	if strings.TrimSpace(s) != "" {
+		for _, part := range strings.Split(s, ",") {
+			words := strings.Fields(part)
+			if len(words) == 0 || len(words) > 2 {
+				return nil, fmt.Errorf("%w: malformed clause %q", ErrInvalidOrderBy, strings.TrimSpace(part))
+			}
+
+			if _, ok := listFields[words[0]]; !ok {
+				return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidOrderBy, words[0])
+			}
+
+			if seen[words[0]] {
+				return nil, fmt.Errorf("%w: duplicate field %q", ErrInvalidOrderBy, words[0])
+			}
+			seen[words[0]] = true
+
+			field := OrderField{Field: words[0]}
+			if len(words) == 2 {
+				switch strings.ToLower(words[1]) {
+				case "asc":
+				case "desc":
+					field.Desc = true
+				default:
+					return nil, fmt.Errorf("%w: unknown direction %q", ErrInvalidOrderBy, words[1])
+				}
+			}
+			order = append(order, field)
+		}
+	}
+
+	if !seen["id"] {
+		order = append(order, OrderField{Field: "id"})
+	}
+
+	return order, nil
+}
+
+func orderByClause(order []OrderField) clause.OrderBy {
+	columns := make([]clause.OrderByColumn, 0, len(order))
+	for _, f := range order {
+		columns = append(columns, clause.OrderByColumn{
+			Column: clause.Column{Name: listFields[f.Field].column},
+			Desc:   f.Desc,
+		})
+	}
+	return clause.OrderBy{Columns: columns}
+}
+
+func filterClause(expr filter.Expr) (clause.Expr, error) {
+	switch e := expr.(type) {
+	case filter.And:
+		return combineClauses(e.Exprs, " AND ")
+	case filter.Or:
+		return combineClauses(e.Exprs, " OR ")
+	case filter.Not:
+		inner, err := filterClause(e.Expr)
+		if err != nil {
+			return clause.Expr{}, err
+		}
+		return clause.Expr{SQL: "NOT (" + inner.SQL + ")", Vars: inner.Vars}, nil
+	case filter.Comparison:
+		return comparisonClause(e)
+	}
+	return clause.Expr{}, fmt.Errorf("%w: unsupported expression %s", ErrInvalidFilter, expr)
+}
+
+func combineClauses(exprs []filter.Expr, sep string) (clause.Expr, error) {
+	sqls := make([]string, 0, len(exprs))
+	var vars []any
+	for _, expr := range exprs {
+		c, err := filterClause(expr)
+		if err != nil {
+			return clause.Expr{}, err
+		}
+		sqls = append(sqls, "("+c.SQL+")")
+		vars = append(vars, c.Vars...)
+	}
+	return clause.Expr{SQL: strings.Join(sqls, sep), Vars: vars}, nil
+}
+
+func comparisonClause(c filter.Comparison) (clause.Expr, error) {
+	field, ok := listFields[c.Field]
+	if !ok || !field.filterable {
+		return clause.Expr{}, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, c.Field)
+	}
+	column := clause.Column{Name: field.column}
+
+	var value any = c.Value
+	switch field.kind {
+	case timeField:
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return clause.Expr{}, fmt.Errorf("%w: %s must be an RFC 3339 timestamp", ErrInvalidFilter, c.Field)
+		}
+		value = t.Local()
+	case stringField:
+		if strings.Contains(c.Value, "*") {
+			return wildcardClause(column, c)
+		}
+	}
+
+	return clause.Expr{SQL: "? " + string(c.Op) + " ?", Vars: []any{column, value}}, nil
+}
+
+// wildcard is a string comparison value with a leading and/or trailing *.
+type wildcard struct {
+	literal        string
+	prefix, suffix bool
+}
+
+func parseWildcard(c filter.Comparison) (wildcard, error) {
+	if c.Op != filter.OpEqual && c.Op != filter.OpNotEqual {
+		return wildcard{}, fmt.Errorf("%w: wildcards are only supported with = and !=", ErrInvalidFilter)
+	}
+
+	w := wildcard{
+		literal: strings.TrimSuffix(strings.TrimPrefix(c.Value, "*"), "*"),
+		prefix:  strings.HasPrefix(c.Value, "*"),
+		suffix:  strings.HasSuffix(c.Value, "*"),
+	}
+	if w.literal == "" || strings.Contains(w.literal, "*") {
+		return wildcard{}, fmt.Errorf("%w: a wildcard is only allowed at the start or end of %s", ErrInvalidFilter, c.Field)
+	}
+	return w, nil
+}
+
+func wildcardClause(column clause.Column, c filter.Comparison) (clause.Expr, error) {
+	w, err := parseWildcard(c)
+	if err != nil {
+		return clause.Expr{}, err
+	}
+
+	pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(w.literal)
+	if w.prefix {
+		pattern = "%" + pattern
+	}
+	if w.suffix {
+		pattern += "%"
+	}
+
+	op := "LIKE"
+	if c.Op == filter.OpNotEqual {
+		op = "NOT LIKE"
+	}
+	return clause.Expr{SQL: "? " + op + ` ? ESCAPE '\'`, Vars: []any{column, pattern}}, nil
+}
+
+func keysetClause(order []OrderField, after *User) clause.Expr {
+	var alternatives []string
+	var vars []any
+
+	for i, f := range order {
+		var parts []string
+		for _, prev := range order[:i] {
+			parts = append(parts, "? = ?")
+			vars = append(vars, clause.Column{Name: listFields[prev.Field].column}, orderValue(after, prev.Field))
+		}
+
+		op := ">"
+		if f.Desc {
+			op = "<"
+		}
+		parts = append(parts, "? "+op+" ?")
+		vars = append(vars, clause.Column{Name: listFields[f.Field].column}, orderValue(after, f.Field))
+
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return clause.Expr{SQL: strings.Join(alternatives, " OR "), Vars: vars}
+}
+
+func orderValue(user *User, field string) any {
+	switch field {
+	case "name":
+		return user.Name
+	case "email":
+		return user.Email
+	case "created_at":
+		return user.CreatedAt
+	}
+	return user.ID
+}
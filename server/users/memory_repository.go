@@ -0,0 +1,533 @@
+package users
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cndrsdrmn/go-grpc/server/filter"
+	"gorm.io/gorm"
+)
+
+// memoryUserRepository keeps users in process memory with the same semantics
+// as userRepository: deleted users stay until purged and keep their email
+// reserved, and every method returns copies so callers never share state.
+type memoryUserRepository struct {
+	mu     sync.RWMutex
+	users  map[uint]*User
+	emails map[string]uint
+	roles  map[uint]map[string]bool
+	nextID uint
+	now    func() time.Time
+}
+
+func (repo *memoryUserRepository) AllUser(ctx context.Context) ([]User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return repo.sorted(false), nil
+}
+
+func (repo *memoryUserRepository) ListUsers(ctx context.Context, query ListQuery) ([]User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	match, err := filterPredicate(query.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	order := query.OrderBy
+	if len(order) == 0 {
+		order = []OrderField{{Field: "id"}}
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	users := []User{}
+	for _, user := range repo.sorted(query.ShowDeleted) {
+		if !match(&user) || (query.After != nil && compareUsers(&user, query.After, order) <= 0) {
+			continue
+		}
+		users = append(users, user)
+	}
+
+	slices.SortFunc(users, func(a, b User) int { return compareUsers(&a, &b, order) })
+	if query.Limit >= 0 && len(users) > query.Limit {
+		users = users[:query.Limit]
+	}
+	return users, nil
+}
+
+func (repo *memoryUserRepository) StreamUsers(ctx context.Context, batchSize int, fn func([]User) error) error {
+	repo.mu.RLock()
+	users := repo.sorted(false)
+	repo.mu.RUnlock()
+
+	for start := 0; start < len(users); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(users[start:min(start+batchSize, len(users))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *memoryUserRepository) CreateUser(ctx context.Context, user *User) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	hashed, err := hashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.insert(user)
+}
+
+func (repo *memoryUserRepository) CreateUsers(ctx context.Context, users []*User) []error {
+	errs := hashPasswords(ctx, users)
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for i, user := range users {
+		if errs[i] == nil {
+			errs[i] = cmp.Or(ctx.Err(), repo.insert(user))
+		}
+	}
+	return errs
+}
+
+func (repo *memoryUserRepository) FindUser(ctx context.Context, id uint) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return &User{}, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	return repo.find(id)
+}
+
+func (repo *memoryUserRepository) FindUserByEmail(ctx context.Context, email string) (*User, error) {
+	if err := ctx.Err(); err != nil {
+		return &User{}, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	id, ok := repo.emails[email]
+	if !ok {
+		return &User{}, ErrUserNotFound
+	}
+	return repo.find(id)
+}
+
+func (repo *memoryUserRepository) UpdateUser(ctx context.Context, id uint, user *User, fields []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	updates, err := updateColumns(user, fields)
+	if err != nil {
+		return err
+	}
+
+	if password, ok := updates["Password"].(string); ok {
+		hashed, err := hashPassword(password)
+		if err != nil {
+			return err
+		}
+		updates["Password"] = hashed
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.users[id]
+	if !ok || stored.DeletedAt.Valid {
+		return ErrUserNotFound
+	}
+	if user.Version != 0 && user.Version != stored.Version {
+		return ErrVersionConflict
+	}
+
+	if email, ok := updates["Email"].(string); ok && email != stored.Email {
+		if _, taken := repo.emails[email]; taken {
+			return ErrEmailAlreadyExists
+		}
+		delete(repo.emails, stored.Email)
+		repo.emails[email] = id
+		stored.Email = email
+	}
+	if name, ok := updates["Name"].(string); ok {
+		stored.Name = name
+	}
+	if password, ok := updates["Password"].(string); ok {
+		stored.Password = password
+	}
+	stored.Version++
+	stored.UpdatedAt = repo.now()
+
+	*user = repo.clone(stored)
+	return nil
+}
+
+func (repo *memoryUserRepository) DeleteUser(ctx context.Context, id uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.users[id]
+	if !ok || stored.DeletedAt.Valid {
+		return ErrUserNotFound
+	}
+	stored.DeletedAt = gorm.DeletedAt{Time: repo.now(), Valid: true}
+	return nil
+}
+
+func (repo *memoryUserRepository) RestoreUser(ctx context.Context, id uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if !stored.DeletedAt.Valid {
+		return ErrUserNotDeleted
+	}
+	stored.DeletedAt = gorm.DeletedAt{}
+	stored.UpdatedAt = repo.now()
+	return nil
+}
+
+func (repo *memoryUserRepository) PurgeUser(ctx context.Context, id uint) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.users[id]
+	if !ok {
+		return ErrUserNotFound
+	}
+	delete(repo.emails, stored.Email)
+	delete(repo.users, id)
+	delete(repo.roles, id)
+	return nil
+}
+
+func (repo *memoryUserRepository) FindRoles(ctx context.Context, id uint) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	if stored, ok := repo.users[id]; ok && stored.DeletedAt.Valid {
+		return nil, nil
+	}
+	return repo.roleNames(id), nil
+}
+
+func (repo *memoryUserRepository) HasRole(ctx context.Context, role string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	for id, roles := range repo.roles {
+		if stored, ok := repo.users[id]; ok && !stored.DeletedAt.Valid && roles[role] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (repo *memoryUserRepository) GrantRole(ctx context.Context, id uint, role string) error {
+	return repo.changeRole(ctx, id, role, func(roles map[string]bool) { roles[role] = true })
+}
+
+func (repo *memoryUserRepository) RevokeRole(ctx context.Context, id uint, role string) error {
+	return repo.changeRole(ctx, id, role, func(roles map[string]bool) { delete(roles, role) })
+}
+
+func (repo *memoryUserRepository) changeRole(ctx context.Context, id uint, role string, change func(map[string]bool)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if _, ok := protoRoles[role]; !ok {
+		return ErrInvalidRole
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if stored, ok := repo.users[id]; !ok || stored.DeletedAt.Valid {
+		return ErrUserNotFound
+	}
+	if repo.roles[id] == nil {
+		repo.roles[id] = map[string]bool{}
+	}
+	change(repo.roles[id])
+	return nil
+}
+
+// insert stores an already hashed user, assigning its ID and timestamps the
+// way the database would. The caller must hold the write lock.
+func (repo *memoryUserRepository) insert(user *User) error {
+	// Like the database, a taken primary key is reported before a taken email.
+	if _, taken := repo.users[user.ID]; taken {
+		return ErrUserIDAlreadyExists
+	}
+	if _, taken := repo.emails[user.Email]; taken {
+		return ErrEmailAlreadyExists
+	}
+	if user.ID == 0 {
+		user.ID = repo.nextID + 1
+	}
+	repo.nextID = max(repo.nextID, user.ID)
+
+	now := repo.now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+	if user.Version == 0 {
+		user.Version = 1
+	}
+
+	stored := *user
+	stored.Roles = nil
+	repo.users[user.ID] = &stored
+	repo.emails[user.Email] = user.ID
+	for i := range user.Roles {
+		user.Roles[i].UserID = user.ID
+		if repo.roles[user.ID] == nil {
+			repo.roles[user.ID] = map[string]bool{}
+		}
+		repo.roles[user.ID][user.Roles[i].Role] = true
+	}
+	return nil
+}
+
+// find returns a copy of a user that is not deleted. The caller must hold
+// the lock.
+func (repo *memoryUserRepository) find(id uint) (*User, error) {
+	stored, ok := repo.users[id]
+	if !ok || stored.DeletedAt.Valid {
+		return &User{}, ErrUserNotFound
+	}
+	user := repo.clone(stored)
+	return &user, nil
+}
+
+// sorted returns copies of the stored users in ID order. The caller must hold
+// the lock.
+func (repo *memoryUserRepository) sorted(withDeleted bool) []User {
+	users := make([]User, 0, len(repo.users))
+	for _, stored := range repo.users {
+		if withDeleted || !stored.DeletedAt.Valid {
+			users = append(users, repo.clone(stored))
+		}
+	}
+	slices.SortFunc(users, func(a, b User) int { return cmp.Compare(a.ID, b.ID) })
+	return users
+}
+
+func (repo *memoryUserRepository) clone(stored *User) User {
+	user := *stored
+	for _, role := range repo.roleNames(stored.ID) {
+		user.Roles = append(user.Roles, UserRole{UserID: stored.ID, Role: role})
+	}
+	return user
+}
+
+func (repo *memoryUserRepository) roleNames(id uint) []string {
+	var roles []string
+	for role := range repo.roles[id] {
+		roles = append(roles, role)
+	}
+	slices.Sort(roles)
+	return roles
+}
+
+func compareUsers(a, b *User, order []OrderField) int {
+	for _, f := range order {
+		var c int
+		switch f.Field {
+		case "name":
+			c = strings.Compare(a.Name, b.Name)
+		case "email":
+			c = strings.Compare(a.Email, b.Email)
+		case "created_at":
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		default:
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		if f.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// filterPredicate is the in-memory counterpart of filterClause and rejects
+// the same filters.
+func filterPredicate(expr filter.Expr) (func(*User) bool, error) {
+	switch e := expr.(type) {
+	case nil:
+		return func(*User) bool { return true }, nil
+	case filter.And:
+		return combinePredicates(e.Exprs, true)
+	case filter.Or:
+		return combinePredicates(e.Exprs, false)
+	case filter.Not:
+		inner, err := filterPredicate(e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return func(user *User) bool { return !inner(user) }, nil
+	case filter.Comparison:
+		return comparisonPredicate(e)
+	}
+	return nil, fmt.Errorf("%w: unsupported expression %s", ErrInvalidFilter, expr)
+}
+
+// combinePredicates joins exprs with AND when all is set and with OR
+// otherwise.
+func combinePredicates(exprs []filter.Expr, all bool) (func(*User) bool, error) {
+	preds := make([]func(*User) bool, 0, len(exprs))
+	for _, expr := range exprs {
+		pred, err := filterPredicate(expr)
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, pred)
+	}
+
+	return func(user *User) bool {
+		for _, pred := range preds {
+			if pred(user) != all {
+				return !all
+			}
+		}
+		return all
+	}, nil
+}
+
+func comparisonPredicate(c filter.Comparison) (func(*User) bool, error) {
+	field, ok := listFields[c.Field]
+	if !ok || !field.filterable {
+		return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, c.Field)
+	}
+
+	if field.kind == timeField {
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be an RFC 3339 timestamp", ErrInvalidFilter, c.Field)
+		}
+		return func(user *User) bool { return compareWith(c.Op, user.CreatedAt.Compare(t)) }, nil
+	}
+
+	value := func(user *User) string { return user.Name }
+	if c.Field == "email" {
+		value = func(user *User) string { return user.Email }
+	}
+
+	if !strings.Contains(c.Value, "*") {
+		return func(user *User) bool { return compareWith(c.Op, strings.Compare(value(user), c.Value)) }, nil
+	}
+
+	w, err := parseWildcard(c)
+	if err != nil {
+		return nil, err
+	}
+	return func(user *User) bool { return w.match(value(user)) == (c.Op == filter.OpEqual) }, nil
+}
+
+func compareWith(op filter.Operator, c int) bool {
+	switch op {
+	case filter.OpEqual:
+		return c == 0
+	case filter.OpNotEqual:
+		return c != 0
+	case filter.OpLess:
+		return c < 0
+	case filter.OpLessEqual:
+		return c <= 0
+	case filter.OpGreater:
+		return c > 0
+	}
+	return c >= 0
+}
+
+// match follows SQLite's LIKE, which ignores case for ASCII letters only.
+func (w wildcard) match(s string) bool {
+	s, literal := lowerASCII(s), lowerASCII(w.literal)
+	switch {
+	case w.prefix && w.suffix:
+		return strings.Contains(s, literal)
+	case w.prefix:
+		return strings.HasSuffix(s, literal)
+	case w.suffix:
+		return strings.HasPrefix(s, literal)
+	}
+	return s == literal
+}
+
+func lowerASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+}
+
+func NewMemoryUserRepository() UserRepositoryInterface {
+	return &memoryUserRepository{
+		users:  map[uint]*User{},
+		emails: map[string]uint{},
+		roles:  map[uint]map[string]bool{},
+		now:    func() time.Time { return time.Now().Local() },
+	}
+}
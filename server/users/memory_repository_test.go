@@ -0,0 +1,30 @@
+package users_test
+
+import (
+	"context"
+	"testing"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"github.com/cndrsdrmn/go-grpc/server/users/userstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRepoConformance(t *testing.T) {
+	userstest.RunRepositoryConformance(t, func(t *testing.T) users.UserRepositoryInterface {
+		return users.NewMemoryUserRepository()
+	})
+}
+
+func TestMemoryRepoBacksUserService(t *testing.T) {
+	srvs := users.NewUserService(users.NewMemoryUserRepository())
+	ctx := context.Background()
+
+	created, err := srvs.CreateUser(ctx, &protos.CreateUserRequest{Name: "John", Email: "john@example.com", Password: "secret"})
+	require.NoError(t, err)
+
+	res, err := srvs.GetUser(ctx, &protos.GetUserRequest{Id: created.User.Id})
+	assert.NoError(t, err)
+	assert.Equal(t, "john@example.com", res.User.Email)
+}
@@ -0,0 +1,101 @@
+package users
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize  = 50
+	maxPageSize      = 1000
+	defaultBatchSize = 100
+	bulkBatchSize    = 100
+)
+
+type pageToken struct {
+	Query     string     `json:"query"`
+	AfterID   uint       `json:"after_id"`
+	Name      *string    `json:"name,omitempty"`
+	Email     *string    `json:"email,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+func newPageToken(query string, order []OrderField, last User) pageToken {
+	token := pageToken{Query: query, AfterID: last.ID}
+	for _, f := range order {
+		switch f.Field {
+		case "name":
+			token.Name = &last.Name
+		case "email":
+			token.Email = &last.Email
+		case "created_at":
+			token.CreatedAt = &last.CreatedAt
+		}
+	}
+	return token
+}
+
+func (token pageToken) cursor() *User {
+	if token.AfterID == 0 {
+		return nil
+	}
+
+	user := &User{Model: gorm.Model{ID: token.AfterID}}
+	if token.Name != nil {
+		user.Name = *token.Name
+	}
+	if token.Email != nil {
+		user.Email = *token.Email
+	}
+	if token.CreatedAt != nil {
+		user.CreatedAt = *token.CreatedAt
+	}
+	return user
+}
+
+func queryFingerprint(filter, orderBy string, showDeleted bool) string {
+	sum := sha256.Sum256([]byte(filter + "\x00" + orderBy + "\x00" + strconv.FormatBool(showDeleted)))
+	return hex.EncodeToString(sum[:8])
+}
+
+func encodePageToken(token pageToken) string {
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodePageToken(s, query string) (pageToken, error) {
+	var token pageToken
+	if s == "" {
+		return token, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return token, ErrInvalidPageToken
+	}
+
+	if err := json.Unmarshal(raw, &token); err != nil || token.AfterID == 0 || token.Query != query {
+		return token, ErrInvalidPageToken
+	}
+
+	return token, nil
+}
+
+func normalizePageSize(size int32) int {
+	switch {
+	case size <= 0:
+		return defaultPageSize
+	case size > maxPageSize:
+		return maxPageSize
+	}
+	return int(size)
+}
@@ -0,0 +1,47 @@
+package users
+
+import (
+	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+)
+
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+)
+
+type UserRole struct {
+	UserID uint   `gorm:"primaryKey"`
+	Role   string `gorm:"primaryKey"`
+}
+
+var protoRoles = map[string]pb.Role{
+	RoleAdmin:   pb.Role_ROLE_ADMIN,
+	RoleManager: pb.Role_ROLE_MANAGER,
+}
+
+func RoleFromProto(role pb.Role) (string, error) {
+	for name, r := range protoRoles {
+		if r == role {
+			return name, nil
+		}
+	}
+	return "", ErrInvalidRole
+}
+
+func (user User) RoleNames() []string {
+	names := make([]string, 0, len(user.Roles))
+	for _, r := range user.Roles {
+		names = append(names, r.Role)
+	}
+	return names
+}
+
+func (user User) protoRoles() []pb.Role {
+	roles := make([]pb.Role, 0, len(user.Roles))
+	for _, r := range user.Roles {
+		if role, ok := protoRoles[r.Role]; ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,80 @@
+package users
+
+import (
+	"fmt"
+
+	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+)
+
+// errEmptyName rejects clearing the name, which every user must have.
+var errEmptyName = FieldViolation{Field: "name", Description: "must not be empty when listed in update_mask"}
+
+// updatableFields maps update mask paths to User fields.
+var updatableFields = map[string]string{
+	"name":     "Name",
+	"email":    "Email",
+	"password": "Password",
+}
+
+func UpdateFields(req *pb.UpdateUserRequest) ([]string, error) {
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		var fields []string
+		if req.Name != "" {
+			fields = append(fields, "name")
+		}
+		if req.Email != nil {
+			fields = append(fields, "email")
+		}
+		if req.Password != nil {
+			fields = append(fields, "password")
+		}
+		return fields, nil
+	}
+
+	var fields []string
+	var violations []FieldViolation
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		switch {
+		case updatableFields[path] == "":
+			violations = append(violations, FieldViolation{
+				Field:       "update_mask",
+				Description: fmt.Sprintf("unknown field %q, must be one of name, email, password", path),
+			})
+		case path == "email" && req.Email == nil, path == "password" && req.Password == nil:
+			violations = append(violations, FieldViolation{Field: path, Description: "must be set when listed in update_mask"})
+		case path == "name" && req.Name == "":
+			violations = append(violations, errEmptyName)
+		case !seen[path]:
+			seen[path] = true
+			fields = append(fields, path)
+		}
+	}
+
+	if len(violations) > 0 {
+		return nil, &ValidationError{Violations: violations}
+	}
+	return fields, nil
+}
+
+func updateColumns(user *User, fields []string) (map[string]interface{}, error) {
+	if len(fields) == 0 {
+		return nil, ErrNoFieldsToUpdate
+	}
+
+	values := map[string]string{"Name": user.Name, "Email": user.Email, "Password": user.Password}
+
+	updates := make(map[string]interface{}, len(fields)+1)
+	for _, field := range fields {
+		column, ok := updatableFields[field]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidUpdateMask, field)
+		}
+		if column == "Name" && user.Name == "" {
+			return nil, &ValidationError{Violations: []FieldViolation{errEmptyName}}
+		}
+		updates[column] = values[column]
+	}
+	return updates, nil
+}
@@ -1,8 +1,15 @@
 package users
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
 	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm"
 )
 
@@ -11,6 +18,8 @@ type User struct {
 	Name     string `gorm:"not null"`
 	Email    string `gorm:"uniqueIndex;not null"`
 	Password string
+	Roles    []UserRole `gorm:"foreignKey:UserID"`
+	Version  uint       `gorm:"not null;default:1"`
 }
 
 func (user *User) BeforeCreate(tx *gorm.DB) error {
@@ -22,24 +31,100 @@ func (user *User) BeforeUpdate(tx *gorm.DB) error {
 		return nil
 	}
 
+	if updates, ok := tx.Statement.Dest.(map[string]interface{}); ok {
+		if password, ok := updates["Password"].(string); ok {
+			return setHashedPassword(tx, password)
+		}
+	}
+
 	return user.hashPassword(tx)
 }
 
 func (user *User) hashPassword(tx *gorm.DB) error {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	return setHashedPassword(tx, user.Password)
+}
+
+func setHashedPassword(tx *gorm.DB, password string) error {
+	hashed, err := hashPassword(password)
 	if err != nil {
 		return err
 	}
-	tx.Statement.SetColumn("Password", string(hashed))
+	tx.Statement.SetColumn("Password", hashed)
+	return nil
+}
+
+// passwordCost is the bcrypt cost used for new password hashes. Existing
+// hashes keep the cost they were created with.
+var passwordCost = bcrypt.DefaultCost
+
+func PasswordCost() int {
+	return passwordCost
+}
+
+// SetPasswordCost changes the bcrypt cost; call it before serving requests.
+func SetPasswordCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	passwordCost = cost
 	return nil
 }
 
+func hashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", ErrPasswordTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// hashPasswords hashes the password of every user in place, spreading the
+// bcrypt work over GOMAXPROCS goroutines, and returns the error of each user.
+func hashPasswords(ctx context.Context, users []*User) []error {
+	errs := make([]error, len(users))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i, user := range users {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+			hashed, err := hashPassword(user.Password)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			user.Password = hashed
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
 func (user User) ToProtoUserResponse() *pb.UserResponse {
-	return &pb.UserResponse{
+	res := &pb.UserResponse{
 		User: &pb.User{
-			Id:    uint64(user.ID),
-			Name:  user.Name,
-			Email: user.Email,
+			Id:        uint64(user.ID),
+			Name:      user.Name,
+			Email:     user.Email,
+			Roles:     user.protoRoles(),
+			Version:   uint64(user.Version),
+			CreatedAt: timestamppb.New(user.CreatedAt),
+			UpdatedAt: timestamppb.New(user.UpdatedAt),
 		},
 	}
+
+	if user.DeletedAt.Valid {
+		res.User.DeletedAt = timestamppb.New(user.DeletedAt.Time)
+	}
+
+	return res
 }
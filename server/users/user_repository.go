@@ -1,64 +1,157 @@
 package users
 
 import (
-	"errors"
+	"context"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// UserRepositoryInterface methods stop their work and return the context's
+// error once ctx is cancelled or its deadline passes.
 type UserRepositoryInterface interface {
-	AllUser() ([]User, error)
-	CreateUser(user *User) error
-	FindUser(id uint) (*User, error)
-	UpdateUser(id uint, user *User) error
-	DeleteUser(id uint) error
+	AllUser(ctx context.Context) ([]User, error)
+	ListUsers(ctx context.Context, query ListQuery) ([]User, error)
+	StreamUsers(ctx context.Context, batchSize int, fn func([]User) error) error
+	CreateUser(ctx context.Context, user *User) error
+	CreateUsers(ctx context.Context, users []*User) []error
+	FindUser(ctx context.Context, id uint) (*User, error)
+	FindUserByEmail(ctx context.Context, email string) (*User, error)
+	UpdateUser(ctx context.Context, id uint, user *User, fields []string) error
+	DeleteUser(ctx context.Context, id uint) error
+	RestoreUser(ctx context.Context, id uint) error
+	PurgeUser(ctx context.Context, id uint) error
+	FindRoles(ctx context.Context, id uint) ([]string, error)
+	// HasRole reports whether any user that is not deleted holds role.
+	HasRole(ctx context.Context, role string) (bool, error)
+	GrantRole(ctx context.Context, id uint, role string) error
+	RevokeRole(ctx context.Context, id uint, role string) error
 }
 
 type userRepository struct {
 	db *gorm.DB
 }
 
-func (repo *userRepository) AllUser() ([]User, error) {
+func (repo *userRepository) AllUser(ctx context.Context) ([]User, error) {
+	db := repo.db.WithContext(ctx)
 	var users []User
-	err := repo.db.Find(&users).Error
-	return users, err
+	err := db.Preload("Roles").Find(&users).Error
+	return users, translateRepositoryError(db, err)
 }
 
-func (repo *userRepository) CreateUser(user *User) error {
-	return repo.db.Create(user).Error
+func (repo *userRepository) ListUsers(ctx context.Context, query ListQuery) ([]User, error) {
+	order := query.OrderBy
+	if len(order) == 0 {
+		order = []OrderField{{Field: "id"}}
+	}
+
+	db := repo.db.WithContext(ctx)
+	stmt := db.Model(&User{}).Preload("Roles")
+	if query.ShowDeleted {
+		stmt = stmt.Unscoped()
+	}
+
+	if query.Filter != nil {
+		where, err := filterClause(query.Filter)
+		if err != nil {
+			return nil, err
+		}
+		stmt = stmt.Where(where)
+	}
+
+	if query.After != nil {
+		stmt = stmt.Where(keysetClause(order, query.After))
+	}
+
+	var users []User
+	err := stmt.Order(orderByClause(order)).Limit(query.Limit).Find(&users).Error
+	return users, translateRepositoryError(db, err)
 }
 
-func (repo *userRepository) FindUser(id uint) (*User, error) {
-	var user User
-	err := repo.db.First(&user, id).Error
-	return &user, err
+func (repo *userRepository) StreamUsers(ctx context.Context, batchSize int, fn func([]User) error) error {
+	db := repo.db.WithContext(ctx)
+	var batch []User
+	err := db.Preload("Roles").FindInBatches(&batch, batchSize, func(tx *gorm.DB, n int) error {
+		return fn(batch)
+	}).Error
+	return translateRepositoryError(db, err)
 }
 
-func (repo *userRepository) UpdateUser(id uint, user *User) error {
-	updates := make(map[string]interface{})
-	if user.Name != "" {
-		updates["Name"] = user.Name
-	}
-	if user.Email != "" {
-		updates["Email"] = user.Email
+func (repo *userRepository) CreateUser(ctx context.Context, user *User) error {
+	db := repo.db.WithContext(ctx)
+	return translateRepositoryError(db, db.Create(user).Error)
+}
+
+func (repo *userRepository) CreateUsers(ctx context.Context, users []*User) []error {
+	errs := hashPasswords(ctx, users)
+
+	db := repo.db.WithContext(ctx)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		tx = tx.Session(&gorm.Session{SkipHooks: true})
+		for i, user := range users {
+			if errs[i] != nil {
+				continue
+			}
+			errs[i] = translateRepositoryError(db, tx.Transaction(func(tx *gorm.DB) error {
+				return tx.Create(user).Error
+			}))
+		}
+		return nil
+	})
+
+	if err != nil {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = translateRepositoryError(db, err)
+			}
+		}
 	}
-	if user.Password != "" {
-		updates["Password"] = user.Password
+
+	return errs
+}
+
+func (repo *userRepository) FindUser(ctx context.Context, id uint) (*User, error) {
+	db := repo.db.WithContext(ctx)
+	var user User
+	err := db.Preload("Roles").First(&user, id).Error
+	return &user, translateRepositoryError(db, err)
+}
+
+func (repo *userRepository) FindUserByEmail(ctx context.Context, email string) (*User, error) {
+	db := repo.db.WithContext(ctx)
+	var user User
+	err := db.Preload("Roles").Where("email = ?", email).First(&user).Error
+	return &user, translateRepositoryError(db, err)
+}
+
+func (repo *userRepository) UpdateUser(ctx context.Context, id uint, user *User, fields []string) error {
+	updates, err := updateColumns(user, fields)
+	if err != nil {
+		return err
 	}
-	if len(updates) == 0 {
-		return errors.New("no fields provided to update")
+
+	db := repo.db.WithContext(ctx)
+
+	updates["Version"] = gorm.Expr("version + 1")
+
+	stmt := db.Model(&User{}).Where("id = ?", id)
+	if user.Version != 0 {
+		stmt = stmt.Where("version = ?", user.Version)
 	}
 
-	res := repo.db.Model(&User{}).Where("id = ?", id).Updates(updates)
+	res := stmt.Updates(updates)
 	if res.Error != nil {
-		return res.Error
+		return translateRepositoryError(db, res.Error)
 	}
 
 	if res.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+		if _, err := repo.FindUser(ctx, id); err != nil {
+			return err
+		}
+		return ErrVersionConflict
 	}
 
-	updated, err := repo.FindUser(id)
+	updated, err := repo.FindUser(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -68,20 +161,113 @@ func (repo *userRepository) UpdateUser(id uint, user *User) error {
 	return nil
 }
 
-func (repo *userRepository) DeleteUser(id uint) error {
-	res := repo.db.Unscoped().Delete(&User{}, id)
+func (repo *userRepository) DeleteUser(ctx context.Context, id uint) error {
+	db := repo.db.WithContext(ctx)
+	res := db.Delete(&User{}, id)
 
 	if res.Error != nil {
-		return res.Error
+		return translateRepositoryError(db, res.Error)
 	}
 
 	if res.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+		return ErrUserNotFound
 	}
 
 	return nil
 }
 
+func (repo *userRepository) RestoreUser(ctx context.Context, id uint) error {
+	db := repo.db.WithContext(ctx)
+	res := db.Unscoped().Model(&User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+
+	if res.Error != nil {
+		return translateRepositoryError(db, res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		if _, err := repo.FindUser(ctx, id); err != nil {
+			return err
+		}
+		return ErrUserNotDeleted
+	}
+
+	return nil
+}
+
+func (repo *userRepository) PurgeUser(ctx context.Context, id uint) error {
+	db := repo.db.WithContext(ctx)
+	return db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Delete(&User{}, id)
+
+		if res.Error != nil {
+			return translateRepositoryError(db, res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+
+		return translateRepositoryError(db, tx.Where("user_id = ?", id).Delete(&UserRole{}).Error)
+	})
+}
+
+func (repo *userRepository) FindRoles(ctx context.Context, id uint) ([]string, error) {
+	db := repo.db.WithContext(ctx)
+	var roles []string
+	err := db.Model(&UserRole{}).
+		Where("user_id = ?", id).
+		Where("NOT EXISTS (SELECT 1 FROM users WHERE users.id = user_roles.user_id AND users.deleted_at IS NOT NULL)").
+		Order("role").
+		Pluck("role", &roles).Error
+	return roles, translateRepositoryError(db, err)
+}
+
+func (repo *userRepository) HasRole(ctx context.Context, role string) (bool, error) {
+	db := repo.db.WithContext(ctx)
+	var count int64
+	err := db.Model(&UserRole{}).
+		Where("role = ?", role).
+		Where("EXISTS (SELECT 1 FROM users WHERE users.id = user_roles.user_id AND users.deleted_at IS NULL)").
+		Count(&count).Error
+	return count > 0, translateRepositoryError(db, err)
+}
+
+func (repo *userRepository) GrantRole(ctx context.Context, id uint, role string) error {
+	if _, ok := protoRoles[role]; !ok {
+		return ErrInvalidRole
+	}
+
+	db := repo.db.WithContext(ctx)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&User{}, id).Error; err != nil {
+			return translateRepositoryError(db, err)
+		}
+
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&UserRole{UserID: id, Role: role}).Error
+		return translateRepositoryError(db, err)
+	})
+}
+
+func (repo *userRepository) RevokeRole(ctx context.Context, id uint, role string) error {
+	if _, ok := protoRoles[role]; !ok {
+		return ErrInvalidRole
+	}
+
+	db := repo.db.WithContext(ctx)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id").First(&User{}, id).Error; err != nil {
+			return translateRepositoryError(db, err)
+		}
+
+		err := tx.Where("user_id = ? AND role = ?", id, role).Delete(&UserRole{}).Error
+		return translateRepositoryError(db, err)
+	})
+}
+
 func NewUserRepository(db *gorm.DB) UserRepositoryInterface {
 	return &userRepository{db}
 }
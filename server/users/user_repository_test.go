@@ -1,9 +1,15 @@
 package users_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/cndrsdrmn/go-grpc/internal/testutil"
+	"github.com/cndrsdrmn/go-grpc/server/filter"
+	"github.com/cndrsdrmn/go-grpc/server/migrations"
 	"github.com/cndrsdrmn/go-grpc/server/users"
+	"github.com/cndrsdrmn/go-grpc/server/users/userstest"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -19,26 +25,63 @@ func TestRepoCreateUser(t *testing.T) {
 	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
 
 	t.Run("success create a new user", func(t *testing.T) {
-		err := repo.CreateUser(user)
+		err := repo.CreateUser(t.Context(), user)
 
 		assert.NoError(t, err)
 		assert.NotEqual(t, uint(0), user.ID)
 	})
 
 	t.Run("failed create an existing user", func(t *testing.T) {
-		err := repo.CreateUser(user)
+		err := repo.CreateUser(t.Context(), &users.User{Name: "John Doe", Email: user.Email, Password: "secret"})
 
 		assert.Error(t, err, gorm.ErrDuplicatedKey)
+		assert.ErrorIs(t, err, users.ErrEmailAlreadyExists)
+	})
+
+	t.Run("failed create an existing id", func(t *testing.T) {
+		err := repo.CreateUser(t.Context(), user)
+
+		assert.ErrorIs(t, err, users.ErrUserIDAlreadyExists)
 	})
 }
 
+func TestRepoCreateUsers(t *testing.T) {
+	repo := setupUserRepositoryTest(t)
+	factoryUserCreate(&users.User{Name: "Charlie", Email: "charlie@example.com", Password: "secret"})
+
+	batch := []*users.User{
+		{Name: "David", Email: "david@example.com", Password: "password"},
+		{Name: "Charlie", Email: "charlie@example.com", Password: "secret"},
+		{Name: "Eve", Email: "eve@example.com", Password: strings.Repeat("x", 73)},
+		{Name: "David Again", Email: "david@example.com", Password: "password"},
+		{Name: "Frank", Email: "frank@example.com", Password: "password"},
+	}
+
+	errs := repo.CreateUsers(t.Context(), batch)
+
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], users.ErrEmailAlreadyExists)
+	assert.ErrorIs(t, errs[2], users.ErrPasswordTooLong)
+	assert.ErrorIs(t, errs[3], users.ErrEmailAlreadyExists)
+	assert.NoError(t, errs[4])
+
+	found, err := repo.FindUser(t.Context(), batch[4].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Frank", found.Name)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(found.Password), []byte("password")))
+
+	all, err := repo.AllUser(t.Context())
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
 func TestRepoFindUser(t *testing.T) {
 	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
 	repo := setupUserRepositoryTest(t)
 	factoryUserCreate(user)
 
 	t.Run("find an existing user", func(t *testing.T) {
-		founded, err := repo.FindUser(user.ID)
+		founded, err := repo.FindUser(t.Context(), user.ID)
 
 		assert.NoError(t, err)
 		assert.Equal(t, user.Name, founded.Name)
@@ -49,9 +92,29 @@ func TestRepoFindUser(t *testing.T) {
 	})
 
 	t.Run("find a non-existing user", func(t *testing.T) {
-		_, err := repo.FindUser(999)
+		_, err := repo.FindUser(t.Context(), 999)
 
 		assert.Error(t, err, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+	})
+}
+
+func TestRepoFindUserByEmail(t *testing.T) {
+	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
+	repo := setupUserRepositoryTest(t)
+	factoryUserCreate(user)
+
+	t.Run("find an existing user", func(t *testing.T) {
+		founded, err := repo.FindUserByEmail(t.Context(), "john@example.com")
+
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, founded.ID)
+	})
+
+	t.Run("find a non-existing user", func(t *testing.T) {
+		_, err := repo.FindUserByEmail(t.Context(), "jane@example.com")
+
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
 	})
 }
 
@@ -60,11 +123,102 @@ func TestRepoAllUsers(t *testing.T) {
 	factoryUserCreate(&users.User{Name: "Charlie", Email: "charlie@example.com", Password: "secret"})
 	factoryUserCreate(&users.User{Name: "David", Email: "david@example.com", Password: "password"})
 
-	users, err := repo.AllUser()
+	users, err := repo.AllUser(t.Context())
 	assert.NoError(t, err)
 	assert.Len(t, users, 2)
 }
 
+func TestRepoListUsers(t *testing.T) {
+	repo := setupUserRepositoryTest(t)
+	factoryUserCreate(&users.User{Name: "Charlie", Email: "charlie@example.com", Password: "secret"})
+	factoryUserCreate(&users.User{Name: "David", Email: "david@example.com", Password: "password"})
+	factoryUserCreate(&users.User{Name: "Eve", Email: "eve@example.com", Password: "password"})
+
+	first, err := repo.ListUsers(t.Context(), users.ListQuery{Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, first, 2)
+	assert.Equal(t, "Charlie", first[0].Name)
+
+	rest, err := repo.ListUsers(t.Context(), users.ListQuery{After: &first[1], Limit: 2})
+	assert.NoError(t, err)
+	assert.Len(t, rest, 1)
+	assert.Equal(t, "Eve", rest[0].Name)
+
+	t.Run("filters and orders", func(t *testing.T) {
+		where, err := filter.Parse(`name != "Charlie" AND email = "*@example.com"`)
+		assert.NoError(t, err)
+
+		order, err := users.ParseOrderBy("name desc")
+		assert.NoError(t, err)
+
+		found, err := repo.ListUsers(t.Context(), users.ListQuery{Filter: where, OrderBy: order, Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, found, 2)
+		assert.Equal(t, "Eve", found[0].Name)
+		assert.Equal(t, "David", found[1].Name)
+
+		found, err = repo.ListUsers(t.Context(), users.ListQuery{Filter: where, OrderBy: order, After: &found[0], Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, found, 1)
+		assert.Equal(t, "David", found[0].Name)
+	})
+
+	t.Run("rejects unknown filter fields", func(t *testing.T) {
+		where, _ := filter.Parse(`password = "secret"`)
+
+		_, err := repo.ListUsers(t.Context(), users.ListQuery{Filter: where, Limit: 10})
+		assert.ErrorIs(t, err, users.ErrInvalidFilter)
+	})
+
+	t.Run("includes deleted users only when asked", func(t *testing.T) {
+		assert.NoError(t, repo.DeleteUser(t.Context(), rest[0].ID))
+
+		found, err := repo.ListUsers(t.Context(), users.ListQuery{Limit: 10})
+		assert.NoError(t, err)
+		assert.Len(t, found, 2)
+
+		found, err = repo.ListUsers(t.Context(), users.ListQuery{Limit: 10, ShowDeleted: true})
+		assert.NoError(t, err)
+		assert.Len(t, found, 3)
+		assert.Equal(t, "Eve", found[2].Name)
+	})
+}
+
+func TestRepoStreamUsers(t *testing.T) {
+	repo := setupUserRepositoryTest(t)
+	factoryUserCreate(&users.User{Name: "Charlie", Email: "charlie@example.com", Password: "secret"})
+	factoryUserCreate(&users.User{Name: "David", Email: "david@example.com", Password: "password"})
+	factoryUserCreate(&users.User{Name: "Eve", Email: "eve@example.com", Password: "password"})
+
+	t.Run("walks the table in batches", func(t *testing.T) {
+		var sizes []int
+		var names []string
+		err := repo.StreamUsers(t.Context(), 2, func(batch []users.User) error {
+			sizes = append(sizes, len(batch))
+			for _, u := range batch {
+				names = append(names, u.Name)
+			}
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 1}, sizes)
+		assert.Equal(t, []string{"Charlie", "David", "Eve"}, names)
+	})
+
+	t.Run("stops when the callback fails", func(t *testing.T) {
+		stop := errors.New("stop")
+		calls := 0
+		err := repo.StreamUsers(t.Context(), 1, func(batch []users.User) error {
+			calls++
+			return stop
+		})
+
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+}
+
 func TestRepoUpdateUser(t *testing.T) {
 	repo := setupUserRepositoryTest(t)
 	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
@@ -72,13 +226,70 @@ func TestRepoUpdateUser(t *testing.T) {
 
 	updated := &users.User{Name: "Lorem Ipsum", Email: "lorem@example.com", Password: "supersecret"}
 
-	err := repo.UpdateUser(user.ID, updated)
+	t.Run("update an existing user", func(t *testing.T) {
+		err := repo.UpdateUser(t.Context(), user.ID, updated, []string{"name", "email", "password"})
 
-	assert.NoError(t, err)
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, user.Email, updated.Email)
+		assert.NotEqual(t, user.Name, updated.Name)
+		assert.NotEqual(t, user.Password, updated.Password)
+
+		err = bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("supersecret"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("update keeps the password when it is not provided", func(t *testing.T) {
+		renamed := &users.User{Name: "Dolor Sit"}
+		err := repo.UpdateUser(t.Context(), user.ID, renamed, []string{"name"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, updated.Password, renamed.Password)
+	})
+
+	t.Run("update without any fields", func(t *testing.T) {
+		err := repo.UpdateUser(t.Context(), user.ID, &users.User{}, nil)
+
+		assert.ErrorIs(t, err, users.ErrNoFieldsToUpdate)
+	})
+
+	t.Run("update a non-existing user", func(t *testing.T) {
+		err := repo.UpdateUser(t.Context(), 999, &users.User{Name: "Charlie"}, []string{"name"})
+
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+		assert.ErrorIs(t, repo.UpdateUser(t.Context(), 999, &users.User{Name: "Charlie", Version: 1}, []string{"name"}), users.ErrUserNotFound)
+	})
+
+	t.Run("update bumps the version", func(t *testing.T) {
+		current, err := repo.FindUser(t.Context(), user.ID)
+		assert.NoError(t, err)
+
+		next := &users.User{Name: "Amet", Version: current.Version}
+		assert.NoError(t, repo.UpdateUser(t.Context(), user.ID, next, []string{"name"}))
+		assert.Equal(t, current.Version+1, next.Version)
+	})
+
+	t.Run("update rejects clearing a listed name", func(t *testing.T) {
+		var invalid *users.ValidationError
+		assert.ErrorAs(t, repo.UpdateUser(t.Context(), user.ID, &users.User{}, []string{"name"}), &invalid)
+
+		assert.NoError(t, repo.UpdateUser(t.Context(), user.ID, &users.User{Name: "Amet"}, []string{"name"}))
+	})
+
+	t.Run("update rejects unknown fields", func(t *testing.T) {
+		err := repo.UpdateUser(t.Context(), user.ID, &users.User{}, []string{"roles"})
+
+		assert.ErrorIs(t, err, users.ErrInvalidUpdateMask)
+	})
+
+	t.Run("update rejects a stale version", func(t *testing.T) {
+		err := repo.UpdateUser(t.Context(), user.ID, &users.User{Name: "Stale", Version: 1}, []string{"name"})
+		assert.ErrorIs(t, err, users.ErrVersionConflict)
 
-	assert.NotEqual(t, user.Email, updated.Email)
-	assert.NotEqual(t, user.Name, updated.Name)
-	assert.NotEqual(t, user.Password, updated.Password)
+		found, err := repo.FindUser(t.Context(), user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Amet", found.Name)
+	})
 }
 
 func TestRepoDeleteUser(t *testing.T) {
@@ -87,14 +298,135 @@ func TestRepoDeleteUser(t *testing.T) {
 	factoryUserCreate(user)
 
 	t.Run("can delete an existing user", func(t *testing.T) {
-		err := repo.DeleteUser(user.ID)
+		err := repo.DeleteUser(t.Context(), user.ID)
 
 		assert.NoError(t, err)
 	})
 
 	t.Run("cannot delete a non-existing user", func(t *testing.T) {
-		err := repo.DeleteUser(999)
+		err := repo.DeleteUser(t.Context(), 999)
 
 		assert.Error(t, err, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+	})
+
+	t.Run("keeps the deleted row", func(t *testing.T) {
+		_, err := repo.FindUser(t.Context(), user.ID)
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+
+		var deleted users.User
+		assert.NoError(t, testDB.Unscoped().First(&deleted, user.ID).Error)
+		assert.True(t, deleted.DeletedAt.Valid)
+	})
+}
+
+func TestRepoRestoreUser(t *testing.T) {
+	repo := setupUserRepositoryTest(t)
+	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
+	factoryUserCreate(user)
+
+	t.Run("cannot restore an active user", func(t *testing.T) {
+		assert.ErrorIs(t, repo.RestoreUser(t.Context(), user.ID), users.ErrUserNotDeleted)
+	})
+
+	t.Run("restores a deleted user", func(t *testing.T) {
+		assert.NoError(t, repo.DeleteUser(t.Context(), user.ID))
+		assert.NoError(t, repo.RestoreUser(t.Context(), user.ID))
+
+		restored, err := repo.FindUser(t.Context(), user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "john@example.com", restored.Email)
+	})
+
+	t.Run("cannot restore a non-existing user", func(t *testing.T) {
+		assert.ErrorIs(t, repo.RestoreUser(t.Context(), 999), users.ErrUserNotFound)
+	})
+}
+
+func TestRepoPurgeUser(t *testing.T) {
+	repo := setupUserRepositoryTest(t)
+	active := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
+	deleted := &users.User{Name: "Jane Doe", Email: "jane@example.com", Password: "secret"}
+	factoryUserCreate(active)
+	factoryUserCreate(deleted)
+	assert.NoError(t, repo.DeleteUser(t.Context(), deleted.ID))
+
+	t.Run("purges active and deleted users", func(t *testing.T) {
+		assert.NoError(t, repo.PurgeUser(t.Context(), active.ID))
+		assert.NoError(t, repo.PurgeUser(t.Context(), deleted.ID))
+
+		var count int64
+		assert.NoError(t, testDB.Unscoped().Model(&users.User{}).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+
+	t.Run("cannot purge a non-existing user", func(t *testing.T) {
+		assert.ErrorIs(t, repo.PurgeUser(t.Context(), active.ID), users.ErrUserNotFound)
+	})
+}
+
+func TestRepoRoles(t *testing.T) {
+	repo := setupUserRepositoryTest(t)
+	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
+	factoryUserCreate(user)
+
+	t.Run("grants roles idempotently", func(t *testing.T) {
+		assert.NoError(t, repo.GrantRole(t.Context(), user.ID, users.RoleManager))
+		assert.NoError(t, repo.GrantRole(t.Context(), user.ID, users.RoleManager))
+		assert.NoError(t, repo.GrantRole(t.Context(), user.ID, users.RoleAdmin))
+
+		roles, err := repo.FindRoles(t.Context(), user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{users.RoleAdmin, users.RoleManager}, roles)
+
+		found, err := repo.FindUser(t.Context(), user.ID)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{users.RoleAdmin, users.RoleManager}, found.RoleNames())
+	})
+
+	t.Run("revokes a role", func(t *testing.T) {
+		assert.NoError(t, repo.RevokeRole(t.Context(), user.ID, users.RoleAdmin))
+
+		roles, err := repo.FindRoles(t.Context(), user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{users.RoleManager}, roles)
+	})
+
+	t.Run("rejects unknown roles and users", func(t *testing.T) {
+		assert.ErrorIs(t, repo.GrantRole(t.Context(), user.ID, "owner"), users.ErrInvalidRole)
+		assert.ErrorIs(t, repo.GrantRole(t.Context(), 999, users.RoleAdmin), users.ErrUserNotFound)
+		assert.ErrorIs(t, repo.RevokeRole(t.Context(), 999, users.RoleAdmin), users.ErrUserNotFound)
+	})
+
+	t.Run("ignores roles of deleted users", func(t *testing.T) {
+		assert.NoError(t, repo.DeleteUser(t.Context(), user.ID))
+
+		roles, err := repo.FindRoles(t.Context(), user.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, roles)
+
+		assert.NoError(t, repo.RestoreUser(t.Context(), user.ID))
+
+		roles, err = repo.FindRoles(t.Context(), user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{users.RoleManager}, roles)
+	})
+
+	t.Run("drops roles with the purged user", func(t *testing.T) {
+		assert.NoError(t, repo.PurgeUser(t.Context(), user.ID))
+
+		var count int64
+		assert.NoError(t, testDB.Model(&users.UserRole{}).Where("user_id = ?", user.ID).Count(&count).Error)
+		assert.Zero(t, count)
+	})
+}
+
+func TestRepoConformance(t *testing.T) {
+	userstest.RunRepositoryConformance(t, func(t *testing.T) users.UserRepositoryInterface {
+		db := testutil.OpenMemoryDB(t)
+		if _, err := migrations.NewMigrator(db).Up(); err != nil {
+			t.Fatalf("failed to migrate database: %v", err)
+		}
+		return users.NewUserRepository(db)
 	})
 }
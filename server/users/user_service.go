@@ -2,8 +2,14 @@ package users
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
 
 	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/filter"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -13,13 +19,15 @@ type UserServiceInterface interface {
 
 type userService struct {
 	pb.UnimplementedUserServiceServer
-	repo UserRepositoryInterface
+	repo   UserRepositoryInterface
+	events *EventBroker
+	locks  userLocks
 }
 
-func (srvs *userService) AllUsers(context.Context, *emptypb.Empty) (*pb.AllUsersResponse, error) {
-	users, err := srvs.repo.AllUser()
+func (srvs *userService) AllUsers(ctx context.Context, _ *emptypb.Empty) (*pb.AllUsersResponse, error) {
+	users, err := srvs.repo.AllUser(ctx)
 	if err != nil {
-		return nil, err
+		return nil, toStatusError(err)
 	}
 
 	var res []*pb.User
@@ -30,6 +38,71 @@ func (srvs *userService) AllUsers(context.Context, *emptypb.Empty) (*pb.AllUsers
 	return &pb.AllUsersResponse{Users: res}, nil
 }
 
+func (srvs *userService) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	where, err := filter.Parse(req.Filter)
+	if err != nil {
+		return nil, toStatusError(fmt.Errorf("%w: %v", ErrInvalidFilter, err))
+	}
+
+	order, err := ParseOrderBy(req.OrderBy)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	fingerprint := queryFingerprint(req.Filter, req.OrderBy, req.ShowDeleted)
+	token, err := decodePageToken(req.PageToken, fingerprint)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	size := normalizePageSize(req.PageSize)
+	users, err := srvs.repo.ListUsers(ctx, ListQuery{
+		Filter:      where,
+		OrderBy:     order,
+		After:       token.cursor(),
+		Limit:       size + 1,
+		ShowDeleted: req.ShowDeleted,
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	res := &pb.ListUsersResponse{}
+	if len(users) > size {
+		users = users[:size]
+		res.NextPageToken = encodePageToken(newPageToken(fingerprint, order, users[size-1]))
+	}
+
+	for _, u := range users {
+		res.Users = append(res.Users, u.ToProtoUserResponse().User)
+	}
+
+	return res, nil
+}
+
+func (srvs *userService) StreamUsers(req *pb.StreamUsersRequest, stream pb.UserService_StreamUsersServer) error {
+	ctx := stream.Context()
+
+	batchSize := int(req.BatchSize)
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	err := srvs.repo.StreamUsers(ctx, batchSize, func(users []User) error {
+		for _, u := range users {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := stream.Send(u.ToProtoUserResponse().User); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return toStatusError(err)
+}
+
 func (srvs *userService) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.UserResponse, error) {
 	user := &User{
 		Name:     req.Name,
@@ -37,54 +110,227 @@ func (srvs *userService) CreateUser(ctx context.Context, req *pb.CreateUserReque
 		Password: req.Password,
 	}
 
-	if err := srvs.repo.CreateUser(user); err != nil {
-		return nil, err
+	defer srvs.locks.create()()
+
+	if err := srvs.repo.CreateUser(ctx, user); err != nil {
+		return nil, toStatusError(err)
 	}
 
-	return user.ToProtoUserResponse(), nil
+	res := user.ToProtoUserResponse()
+	srvs.events.Publish(pb.UserEvent_CREATED, res.User)
+
+	return res, nil
+}
+
+func (srvs *userService) BulkCreateUsers(stream pb.UserService_BulkCreateUsersServer) error {
+	ctx := stream.Context()
+	res := &pb.BulkCreateUsersResponse{}
+
+	var batch []*User
+	var indexes []int32
+	flush := func() {
+		defer srvs.locks.create()()
+
+		for i, err := range srvs.repo.CreateUsers(ctx, batch) {
+			result := &pb.BulkCreateUserResult{Index: indexes[i]}
+			if err != nil {
+				result.ErrorReason, result.ErrorMessage = errorReason(err)
+			} else {
+				result.Id = uint64(batch[i].ID)
+				srvs.events.Publish(pb.UserEvent_CREATED, batch[i].ToProtoUserResponse().User)
+			}
+			res.Results = append(res.Results, result)
+		}
+		batch, indexes = nil, nil
+	}
+
+	for index := int32(0); ; index++ {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return toStatusError(err)
+		}
+
+		if err := Validate(req); err != nil {
+			result := &pb.BulkCreateUserResult{Index: index}
+			result.ErrorReason, result.ErrorMessage = errorReason(err)
+			res.Results = append(res.Results, result)
+			continue
+		}
+
+		batch = append(batch, &User{Name: req.Name, Email: req.Email, Password: req.Password})
+		indexes = append(indexes, index)
+		if len(batch) == bulkBatchSize {
+			flush()
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+
+	sort.Slice(res.Results, func(i, j int) bool { return res.Results[i].Index < res.Results[j].Index })
+	for _, result := range res.Results {
+		if result.ErrorReason == "" {
+			res.CreatedCount++
+		} else {
+			res.FailedCount++
+		}
+	}
+
+	return stream.SendAndClose(res)
 }
 
 func (srvs *userService) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.DeleteUserResponse, error) {
-	err := srvs.repo.DeleteUser(uint(req.Id))
+	defer srvs.locks.lock(uint(req.Id))()
+
+	err := srvs.repo.DeleteUser(ctx, uint(req.Id))
 	if err != nil {
-		return &pb.DeleteUserResponse{Success: false}, err
+		return &pb.DeleteUserResponse{Success: false}, toStatusError(err)
+	}
+
+	srvs.events.Publish(pb.UserEvent_DELETED, &pb.User{Id: req.Id})
+
+	return &pb.DeleteUserResponse{Success: true}, nil
+}
+
+func (srvs *userService) RestoreUser(ctx context.Context, req *pb.RestoreUserRequest) (*pb.UserResponse, error) {
+	defer srvs.locks.lock(uint(req.Id))()
+
+	if err := srvs.repo.RestoreUser(ctx, uint(req.Id)); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	user, err := srvs.repo.FindUser(ctx, uint(req.Id))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	res := user.ToProtoUserResponse()
+	srvs.events.Publish(pb.UserEvent_CREATED, res.User)
+
+	return res, nil
+}
+
+func (srvs *userService) PurgeUser(ctx context.Context, req *pb.PurgeUserRequest) (*pb.DeleteUserResponse, error) {
+	defer srvs.locks.lock(uint(req.Id))()
+
+	// A soft-deleted user was already announced as DELETED.
+	_, err := srvs.repo.FindUser(ctx, uint(req.Id))
+	active := err == nil
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return &pb.DeleteUserResponse{Success: false}, toStatusError(err)
+	}
+
+	if err := srvs.repo.PurgeUser(ctx, uint(req.Id)); err != nil {
+		return &pb.DeleteUserResponse{Success: false}, toStatusError(err)
+	}
+
+	if active {
+		srvs.events.Publish(pb.UserEvent_DELETED, &pb.User{Id: req.Id})
 	}
 
 	return &pb.DeleteUserResponse{Success: true}, nil
 }
 
 func (srvs *userService) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.UserResponse, error) {
-	user, err := srvs.repo.FindUser(uint(req.Id))
+	user, err := srvs.repo.FindUser(ctx, uint(req.Id))
 	if err != nil {
-		return nil, err
+		return nil, toStatusError(err)
 	}
 
 	return user.ToProtoUserResponse(), nil
 }
 
 func (srvs *userService) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UserResponse, error) {
-	user, err := srvs.repo.FindUser(uint(req.Id))
+	fields, err := UpdateFields(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	user := &User{
+		Name:     req.Name,
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+		Version:  uint(req.Version),
+	}
+
+	defer srvs.locks.lock(uint(req.Id))()
+	if err := srvs.repo.UpdateUser(ctx, uint(req.Id), user, fields); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	res := user.ToProtoUserResponse()
+	srvs.events.Publish(pb.UserEvent_UPDATED, res.User)
+
+	return res, nil
+}
+
+func (srvs *userService) GrantRole(ctx context.Context, req *pb.RoleRequest) (*pb.UserResponse, error) {
+	return srvs.changeRole(ctx, req, srvs.repo.GrantRole)
+}
+
+func (srvs *userService) RevokeRole(ctx context.Context, req *pb.RoleRequest) (*pb.UserResponse, error) {
+	return srvs.changeRole(ctx, req, srvs.repo.RevokeRole)
+}
+
+func (srvs *userService) changeRole(ctx context.Context, req *pb.RoleRequest, change func(ctx context.Context, id uint, role string) error) (*pb.UserResponse, error) {
+	role, err := RoleFromProto(req.Role)
 	if err != nil {
-		return nil, err
+		return nil, toStatusError(err)
 	}
 
-	user.Name = req.Name
+	defer srvs.locks.lock(uint(req.UserId))()
 
-	if req.Email != nil {
-		user.Email = *req.Email
+	if err := change(ctx, uint(req.UserId), role); err != nil {
+		return nil, toStatusError(err)
 	}
 
-	if req.Password != nil {
-		user.Password = *req.Password
+	user, err := srvs.repo.FindUser(ctx, uint(req.UserId))
+	if err != nil {
+		return nil, toStatusError(err)
 	}
 
-	if err := srvs.repo.UpdateUser(user.ID, user); err != nil {
-		return nil, err
+	res := user.ToProtoUserResponse()
+	srvs.events.Publish(pb.UserEvent_UPDATED, res.User)
+
+	return res, nil
+}
+
+func (srvs *userService) WatchUsers(req *pb.WatchUsersRequest, stream pb.UserService_WatchUsersServer) error {
+	backlog, events, cancel, err := srvs.events.Subscribe(req.SinceRevision)
+	if err != nil {
+		return toStatusError(err)
 	}
+	defer cancel()
 
-	return user.ToProtoUserResponse(), nil
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for _, event := range backlog {
+		if err := stream.Send(event); err != nil {
+			return err
+		}
+	}
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return toStatusError(ctx.Err())
+		case event, ok := <-events:
+			if !ok {
+				return toStatusError(ErrWatcherTooSlow)
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
 }
 
 func NewUserService(repo UserRepositoryInterface) UserServiceInterface {
-	return &userService{repo: repo}
+	return &userService{repo: repo, events: NewEventBroker(defaultEventHistory)}
 }
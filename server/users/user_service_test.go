@@ -2,12 +2,22 @@ package users_test
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
 	"github.com/cndrsdrmn/go-grpc/server/users"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"gorm.io/gorm"
 )
 
@@ -35,7 +45,72 @@ func TestSrvsCreateUser(t *testing.T) {
 		_, err := srvs.CreateUser(ctx, req)
 
 		assert.Error(t, err, gorm.ErrDuplicatedKey)
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
 	})
+
+	t.Run("failed create a user with a too long password", func(t *testing.T) {
+		_, err := srvs.CreateUser(ctx, &protos.CreateUserRequest{
+			Name:     "Jane Doe",
+			Email:    "jane@example.com",
+			Password: strings.Repeat("x", 73),
+		})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+type fakeBulkCreateStream struct {
+	grpc.ServerStream
+	reqs []*protos.CreateUserRequest
+	res  *protos.BulkCreateUsersResponse
+}
+
+func (s *fakeBulkCreateStream) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeBulkCreateStream) Recv() (*protos.CreateUserRequest, error) {
+	if len(s.reqs) == 0 {
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return req, nil
+}
+
+func (s *fakeBulkCreateStream) SendAndClose(res *protos.BulkCreateUsersResponse) error {
+	s.res = res
+	return nil
+}
+
+func TestSrvsBulkCreateUsers(t *testing.T) {
+	srvs := setupUserServiceTest(t)
+	defer teardownTest(t)
+
+	stream := &fakeBulkCreateStream{reqs: []*protos.CreateUserRequest{
+		{Name: "Charlie", Email: "charlie@example.com", Password: "secret"},
+		{Name: "David", Email: "not-an-email", Password: "secret"},
+		{Name: "Charlie Again", Email: "charlie@example.com", Password: "secret"},
+		{Name: "Eve", Email: "eve@example.com", Password: "secret"},
+	}}
+
+	err := srvs.BulkCreateUsers(stream)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), stream.res.CreatedCount)
+	assert.Equal(t, int32(2), stream.res.FailedCount)
+
+	results := stream.res.Results
+	assert.Len(t, results, 4)
+	for i, result := range results {
+		assert.Equal(t, int32(i), result.Index)
+	}
+	assert.NotZero(t, results[0].Id)
+	assert.Equal(t, users.ReasonInvalidArgument, results[1].ErrorReason)
+	assert.Contains(t, results[1].ErrorMessage, "email")
+	assert.Equal(t, users.ReasonEmailAlreadyExists, results[2].ErrorReason)
+	assert.Zero(t, results[2].Id)
+	assert.NotZero(t, results[3].Id)
 }
 
 func TestSrvsGetUser(t *testing.T) {
@@ -58,6 +133,25 @@ func TestSrvsGetUser(t *testing.T) {
 		_, err := srvs.GetUser(ctx, &protos.GetUserRequest{Id: 999})
 
 		assert.Error(t, err, gorm.ErrRecordNotFound)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("stops when the client cancels", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := srvs.GetUser(ctx, &protos.GetUserRequest{Id: uint64(user.ID)})
+
+		assert.Equal(t, codes.Canceled, status.Code(err))
+	})
+
+	t.Run("stops when the deadline passes", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), -1)
+		defer cancel()
+
+		_, err := srvs.GetUser(ctx, &protos.GetUserRequest{Id: uint64(user.ID)})
+
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
 	})
 }
 
@@ -73,6 +167,330 @@ func TestSrvsAllUsers(t *testing.T) {
 	assert.Len(t, res.Users, 2)
 }
 
+func TestSrvsListUsers(t *testing.T) {
+	srvs := setupUserServiceTest(t)
+	ctx := context.Background()
+
+	factoryUserCreate(&users.User{Name: "Charlie", Email: "charlie@example.com", Password: "secret"})
+	factoryUserCreate(&users.User{Name: "David", Email: "david@example.com", Password: "password"})
+	factoryUserCreate(&users.User{Name: "Eve", Email: "eve@example.com", Password: "password"})
+
+	t.Run("walks every page", func(t *testing.T) {
+		var names []string
+		req := &protos.ListUsersRequest{PageSize: 2}
+		for {
+			res, err := srvs.ListUsers(ctx, req)
+			assert.NoError(t, err)
+			for _, u := range res.Users {
+				names = append(names, u.Name)
+			}
+			if res.NextPageToken == "" {
+				break
+			}
+			req.PageToken = res.NextPageToken
+		}
+
+		assert.Equal(t, []string{"Charlie", "David", "Eve"}, names)
+	})
+
+	t.Run("uses the default page size", func(t *testing.T) {
+		res, err := srvs.ListUsers(ctx, &protos.ListUsersRequest{})
+
+		assert.NoError(t, err)
+		assert.Len(t, res.Users, 3)
+		assert.Empty(t, res.NextPageToken)
+	})
+
+	t.Run("rejects an invalid page token", func(t *testing.T) {
+		_, err := srvs.ListUsers(ctx, &protos.ListUsersRequest{PageToken: "not-a-token"})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("rejects a page token issued for another query", func(t *testing.T) {
+		res, err := srvs.ListUsers(ctx, &protos.ListUsersRequest{PageSize: 1})
+		assert.NoError(t, err)
+
+		_, err = srvs.ListUsers(ctx, &protos.ListUsersRequest{PageSize: 1, PageToken: res.NextPageToken, OrderBy: "name"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = srvs.ListUsers(ctx, &protos.ListUsersRequest{PageSize: 1, PageToken: res.NextPageToken, ShowDeleted: true})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestSrvsListUsersFilterAndOrder(t *testing.T) {
+	srvs := setupUserServiceTest(t)
+	ctx := context.Background()
+	defer teardownTest(t)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Local()
+	factoryUserCreate(&users.User{Name: "Alice", Email: "alice@example.com", Password: "secret", Model: gorm.Model{CreatedAt: base}})
+	factoryUserCreate(&users.User{Name: "Albert", Email: "albert@corp.test", Password: "secret", Model: gorm.Model{CreatedAt: base.Add(24 * time.Hour)}})
+	factoryUserCreate(&users.User{Name: "Bob", Email: "bob@example.com", Password: "secret", Model: gorm.Model{CreatedAt: base.Add(48 * time.Hour)}})
+	factoryUserCreate(&users.User{Name: "Alfred", Email: "alfred@example.com", Password: "secret", Model: gorm.Model{CreatedAt: base.Add(72 * time.Hour)}})
+
+	names := func(res *protos.ListUsersResponse) []string {
+		var out []string
+		for _, u := range res.Users {
+			out = append(out, u.Name)
+		}
+		return out
+	}
+
+	t.Run("name prefix ordered by name", func(t *testing.T) {
+		res, err := srvs.ListUsers(ctx, &protos.ListUsersRequest{Filter: `name = "Al*"`, OrderBy: "name"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Albert", "Alfred", "Alice"}, names(res))
+	})
+
+	t.Run("email domain ordered by email descending", func(t *testing.T) {
+		res, err := srvs.ListUsers(ctx, &protos.ListUsersRequest{Filter: `email = "*@example.com"`, OrderBy: "email desc"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Bob", "Alice", "Alfred"}, names(res))
+	})
+
+	t.Run("creation time range", func(t *testing.T) {
+		res, err := srvs.ListUsers(ctx, &protos.ListUsersRequest{
+			Filter:  `created_at >= "2024-01-02T00:00:00Z" AND created_at < "2024-01-04T00:00:00Z"`,
+			OrderBy: "created_at desc",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Bob", "Albert"}, names(res))
+	})
+
+	t.Run("pages through an ordered result", func(t *testing.T) {
+		req := &protos.ListUsersRequest{Filter: `NOT name = "Bob"`, OrderBy: "name desc", PageSize: 2}
+		first, err := srvs.ListUsers(ctx, req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Alice", "Alfred"}, names(first))
+
+		req.PageToken = first.NextPageToken
+		second, err := srvs.ListUsers(ctx, req)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Albert"}, names(second))
+		assert.Empty(t, second.NextPageToken)
+	})
+
+	t.Run("rejects invalid queries", func(t *testing.T) {
+		for _, req := range []*protos.ListUsersRequest{
+			{Filter: `name =`},
+			{Filter: `password = "secret"`},
+			{Filter: `created_at > "yesterday"`},
+			{Filter: `name > "A*"`},
+			{Filter: `name = "A*b*"`},
+			{OrderBy: "password"},
+			{OrderBy: "name sideways"},
+			{OrderBy: "name, name desc"},
+		} {
+			_, err := srvs.ListUsers(ctx, req)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err), req.String())
+		}
+	})
+}
+
+type fakeUserStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	users []*protos.User
+}
+
+func (s *fakeUserStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeUserStream) Send(user *protos.User) error {
+	s.users = append(s.users, user)
+	return nil
+}
+
+func TestSrvsStreamUsers(t *testing.T) {
+	srvs := setupUserServiceTest(t)
+	defer teardownTest(t)
+
+	factoryUserCreate(&users.User{Name: "Charlie", Email: "charlie@example.com", Password: "secret"})
+	factoryUserCreate(&users.User{Name: "David", Email: "david@example.com", Password: "password"})
+	factoryUserCreate(&users.User{Name: "Eve", Email: "eve@example.com", Password: "password"})
+
+	t.Run("sends every user", func(t *testing.T) {
+		stream := &fakeUserStream{ctx: context.Background()}
+		err := srvs.StreamUsers(&protos.StreamUsersRequest{BatchSize: 2}, stream)
+
+		assert.NoError(t, err)
+		assert.Len(t, stream.users, 3)
+		assert.Equal(t, "Eve", stream.users[2].Name)
+	})
+
+	t.Run("stops when the client cancels", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		stream := &fakeUserStream{ctx: ctx}
+		err := srvs.StreamUsers(&protos.StreamUsersRequest{}, stream)
+
+		assert.Equal(t, codes.Canceled, status.Code(err))
+		assert.Empty(t, stream.users)
+	})
+}
+
+type fakeWatchStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	ready  chan struct{}
+	events chan *protos.UserEvent
+}
+
+func (s *fakeWatchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeWatchStream) SendHeader(metadata.MD) error {
+	close(s.ready)
+	return nil
+}
+
+func (s *fakeWatchStream) Send(event *protos.UserEvent) error {
+	s.events <- event
+	return nil
+}
+
+func TestSrvsWatchUsers(t *testing.T) {
+	srvs := setupUserServiceTest(t)
+	ctx := context.Background()
+	defer teardownTest(t)
+
+	created, err := srvs.CreateUser(ctx, &protos.CreateUserRequest{Name: "John Doe", Email: "john@example.com", Password: "secret"})
+	assert.NoError(t, err)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	stream := &fakeWatchStream{ctx: watchCtx, ready: make(chan struct{}), events: make(chan *protos.UserEvent, 10)}
+	done := make(chan error)
+	go func() { done <- srvs.WatchUsers(&protos.WatchUsersRequest{}, stream) }()
+	<-stream.ready
+
+	_, err = srvs.DeleteUser(ctx, &protos.DeleteUserRequest{Id: created.User.Id})
+	assert.NoError(t, err)
+
+	event := <-stream.events
+	assert.Equal(t, protos.UserEvent_DELETED, event.Type)
+	assert.Equal(t, created.User.Id, event.User.Id)
+
+	_, err = srvs.PurgeUser(ctx, &protos.PurgeUserRequest{Id: created.User.Id})
+	assert.NoError(t, err)
+	_, err = srvs.CreateUser(ctx, &protos.CreateUserRequest{Name: "Jane Doe", Email: "jane@example.com", Password: "secret"})
+	assert.NoError(t, err)
+
+	event = <-stream.events
+	assert.Equal(t, protos.UserEvent_CREATED, event.Type, "purging a deleted user must not announce it again")
+
+	cancel()
+	assert.Equal(t, codes.Canceled, status.Code(<-done))
+
+	t.Run("rejects an unavailable revision", func(t *testing.T) {
+		stream := &fakeWatchStream{ctx: ctx, ready: make(chan struct{}), events: make(chan *protos.UserEvent, 10)}
+		err := srvs.WatchUsers(&protos.WatchUsersRequest{SinceRevision: 1000}, stream)
+
+		assert.Equal(t, codes.OutOfRange, status.Code(err))
+	})
+}
+
+func TestSrvsWatchUsersOrdersConcurrentUpdates(t *testing.T) {
+	const writers = 10
+	srvs := setupUserServiceTest(t)
+	ctx := context.Background()
+	defer teardownTest(t)
+
+	created, err := srvs.CreateUser(ctx, &protos.CreateUserRequest{Name: "John Doe", Email: "john@example.com", Password: "secret"})
+	assert.NoError(t, err)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := &fakeWatchStream{ctx: watchCtx, ready: make(chan struct{}), events: make(chan *protos.UserEvent, writers)}
+	go srvs.WatchUsers(&protos.WatchUsersRequest{}, stream)
+	<-stream.ready
+
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := srvs.UpdateUser(ctx, &protos.UpdateUserRequest{
+				Id:         created.User.Id,
+				Name:       fmt.Sprintf("Writer %d", i),
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	var last uint64
+	for range writers {
+		event := <-stream.events
+		assert.Greater(t, event.User.Version, last, "events must follow the commit order")
+		last = event.User.Version
+	}
+}
+
+// pausingRepository holds a create after its insert until release is closed.
+type pausingRepository struct {
+	users.UserRepositoryInterface
+	inserted chan struct{}
+	release  chan struct{}
+}
+
+func (repo pausingRepository) CreateUser(ctx context.Context, user *users.User) error {
+	err := repo.UserRepositoryInterface.CreateUser(ctx, user)
+	close(repo.inserted)
+	<-repo.release
+	return err
+}
+
+func TestSrvsWatchUsersAnnouncesACreatedUserFirst(t *testing.T) {
+	repo := pausingRepository{users.NewMemoryUserRepository(), make(chan struct{}), make(chan struct{})}
+	srvs := users.NewUserService(repo)
+	ctx := context.Background()
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := &fakeWatchStream{ctx: watchCtx, ready: make(chan struct{}), events: make(chan *protos.UserEvent, 2)}
+	go srvs.WatchUsers(&protos.WatchUsersRequest{}, stream)
+	<-stream.ready
+
+	created := make(chan error)
+	go func() {
+		_, err := srvs.CreateUser(ctx, &protos.CreateUserRequest{Name: "John Doe", Email: "john@example.com", Password: "secret"})
+		created <- err
+	}()
+	<-repo.inserted
+
+	// The user exists but its CREATED event is not out yet.
+	updated := make(chan error)
+	go func() {
+		_, err := srvs.UpdateUser(ctx, &protos.UpdateUserRequest{
+			Id:         1,
+			Name:       "Charlie",
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+		})
+		updated <- err
+	}()
+
+	select {
+	case err := <-updated:
+		t.Fatalf("update finished before the create was announced: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(repo.release)
+
+	assert.NoError(t, <-created)
+	assert.NoError(t, <-updated)
+	assert.Equal(t, protos.UserEvent_CREATED, (<-stream.events).Type)
+	assert.Equal(t, protos.UserEvent_UPDATED, (<-stream.events).Type)
+}
+
 func TestSrvsUpdateUser(t *testing.T) {
 	srvs := setupUserServiceTest(t)
 	ctx := context.Background()
@@ -80,11 +498,78 @@ func TestSrvsUpdateUser(t *testing.T) {
 	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
 	factoryUserCreate(user)
 
-	res, err := srvs.UpdateUser(ctx, &protos.UpdateUserRequest{Id: uint64(user.ID), Name: "Charlie"})
+	t.Run("update an existing user", func(t *testing.T) {
+		res, err := srvs.UpdateUser(ctx, &protos.UpdateUserRequest{Id: uint64(user.ID), Name: "Charlie"})
 
-	assert.NoError(t, err)
-	assert.Equal(t, "Charlie", res.User.Name)
-	assert.Equal(t, "john@example.com", res.User.Email)
+		assert.NoError(t, err)
+		assert.Equal(t, "Charlie", res.User.Name)
+		assert.Equal(t, "john@example.com", res.User.Email)
+	})
+
+	t.Run("update a non-existing user", func(t *testing.T) {
+		_, err := srvs.UpdateUser(ctx, &protos.UpdateUserRequest{Id: 999, Name: "Charlie"})
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("update only the masked fields", func(t *testing.T) {
+		email := "ignored@example.com"
+		res, err := srvs.UpdateUser(ctx, &protos.UpdateUserRequest{
+			Id:         uint64(user.ID),
+			Name:       "John Masked",
+			Email:      &email,
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "John Masked", res.User.Name)
+		assert.Equal(t, "john@example.com", res.User.Email)
+	})
+
+	t.Run("reject clearing the name through the mask", func(t *testing.T) {
+		_, err := srvs.UpdateUser(ctx, &protos.UpdateUserRequest{
+			Id:         uint64(user.ID),
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+		})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		res, err := srvs.GetUser(ctx, &protos.GetUserRequest{Id: uint64(user.ID)})
+		assert.NoError(t, err)
+		assert.Equal(t, "John Masked", res.User.Name)
+	})
+
+	t.Run("reject an invalid mask", func(t *testing.T) {
+		_, err := srvs.UpdateUser(ctx, &protos.UpdateUserRequest{
+			Id:         uint64(user.ID),
+			Name:       "John",
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name", "roles", "email"}},
+		})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, map[string]string{
+			"update_mask": `unknown field "roles", must be one of name, email, password`,
+			"email":       "must be set when listed in update_mask",
+		}, fieldViolations(t, err))
+	})
+
+	t.Run("reject a concurrent update", func(t *testing.T) {
+		current, err := srvs.GetUser(ctx, &protos.GetUserRequest{Id: uint64(user.ID)})
+		assert.NoError(t, err)
+
+		first, err := srvs.UpdateUser(ctx, &protos.UpdateUserRequest{Id: uint64(user.ID), Name: "First", Version: current.User.Version})
+		assert.NoError(t, err)
+		assert.Equal(t, current.User.Version+1, first.User.Version)
+		assert.False(t, first.User.UpdatedAt.AsTime().Before(current.User.UpdatedAt.AsTime()))
+		assert.Equal(t, current.User.CreatedAt.AsTime(), first.User.CreatedAt.AsTime())
+
+		_, err = srvs.UpdateUser(ctx, &protos.UpdateUserRequest{Id: uint64(user.ID), Name: "Second", Version: current.User.Version})
+		assert.Equal(t, codes.Aborted, status.Code(err))
+
+		if info := errorInfo(err); assert.NotNil(t, info) {
+			assert.Equal(t, users.ReasonVersionConflict, info.Reason)
+		}
+	})
 }
 
 func TestSrvsDeleteUser(t *testing.T) {
@@ -105,6 +590,93 @@ func TestSrvsDeleteUser(t *testing.T) {
 		res, err := srvs.DeleteUser(ctx, &protos.DeleteUserRequest{Id: uint64(user.ID)})
 
 		assert.Error(t, err, gorm.ErrRecordNotFound)
+		assert.Equal(t, codes.NotFound, status.Code(err))
 		assert.False(t, res.Success)
 	})
 }
+
+func TestSrvsRestoreUser(t *testing.T) {
+	srvs := setupUserServiceTest(t)
+	ctx := context.Background()
+	defer teardownTest(t)
+
+	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
+	factoryUserCreate(user)
+
+	t.Run("restore a deleted user", func(t *testing.T) {
+		_, err := srvs.DeleteUser(ctx, &protos.DeleteUserRequest{Id: uint64(user.ID)})
+		assert.NoError(t, err)
+
+		listed, err := srvs.ListUsers(ctx, &protos.ListUsersRequest{ShowDeleted: true})
+		assert.NoError(t, err)
+		assert.Len(t, listed.Users, 1)
+		assert.NotNil(t, listed.Users[0].DeletedAt)
+
+		res, err := srvs.RestoreUser(ctx, &protos.RestoreUserRequest{Id: uint64(user.ID)})
+		assert.NoError(t, err)
+		assert.Equal(t, "john@example.com", res.User.Email)
+		assert.Nil(t, res.User.DeletedAt)
+
+		_, err = srvs.GetUser(ctx, &protos.GetUserRequest{Id: uint64(user.ID)})
+		assert.NoError(t, err)
+	})
+
+	t.Run("restore an active user", func(t *testing.T) {
+		_, err := srvs.RestoreUser(ctx, &protos.RestoreUserRequest{Id: uint64(user.ID)})
+
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func TestSrvsPurgeUser(t *testing.T) {
+	srvs := setupUserServiceTest(t)
+	ctx := context.Background()
+
+	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
+	factoryUserCreate(user)
+
+	t.Run("purge a user", func(t *testing.T) {
+		res, err := srvs.PurgeUser(ctx, &protos.PurgeUserRequest{Id: uint64(user.ID)})
+		assert.NoError(t, err)
+		assert.True(t, res.Success)
+
+		_, err = srvs.RestoreUser(ctx, &protos.RestoreUserRequest{Id: uint64(user.ID)})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("purge a non-existing user", func(t *testing.T) {
+		res, err := srvs.PurgeUser(ctx, &protos.PurgeUserRequest{Id: uint64(user.ID)})
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.False(t, res.Success)
+	})
+}
+
+func TestSrvsGrantAndRevokeRole(t *testing.T) {
+	srvs := setupUserServiceTest(t)
+	defer teardownTest(t)
+	ctx := context.Background()
+
+	user := &users.User{Name: "John Doe", Email: "john@example.com", Password: "secret"}
+	factoryUserCreate(user)
+
+	t.Run("grant a role", func(t *testing.T) {
+		res, err := srvs.GrantRole(ctx, &protos.RoleRequest{UserId: uint64(user.ID), Role: protos.Role_ROLE_MANAGER})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []protos.Role{protos.Role_ROLE_MANAGER}, res.User.Roles)
+	})
+
+	t.Run("revoke a role", func(t *testing.T) {
+		res, err := srvs.RevokeRole(ctx, &protos.RoleRequest{UserId: uint64(user.ID), Role: protos.Role_ROLE_MANAGER})
+
+		assert.NoError(t, err)
+		assert.Empty(t, res.User.Roles)
+	})
+
+	t.Run("grant a role to a non-existing user", func(t *testing.T) {
+		_, err := srvs.GrantRole(ctx, &protos.RoleRequest{UserId: 999, Role: protos.Role_ROLE_ADMIN})
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
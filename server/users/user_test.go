@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/cndrsdrmn/go-grpc/server/users"
 	"github.com/stretchr/testify/assert"
@@ -23,6 +24,10 @@ func teardownTest(t *testing.T) {
 		t.Fatalf("failed to clear users table: %v", err)
 	}
 
+	if err := testDB.Exec("DELETE FROM user_roles").Error; err != nil {
+		t.Fatalf("failed to clear user_roles table: %v", err)
+	}
+
 	if err := testDB.Exec("DELETE FROM sqlite_sequence WHERE name='users'").Error; err != nil {
 		t.Fatalf("failed to reset autoincrement sequence: %v", err)
 	}
@@ -35,7 +40,7 @@ func TestMain(m *testing.M) {
 		log.Fatalf("failed to connect to the database: %v", err)
 	}
 
-	if err := db.AutoMigrate(&users.User{}); err != nil {
+	if err := db.AutoMigrate(&users.User{}, &users.UserRole{}); err != nil {
 		log.Fatalf("failed to migrate database: %v", err)
 	}
 
@@ -97,4 +102,12 @@ func TestStructToProtoUserResponse(t *testing.T) {
 	assert.Equal(t, uint64(1), resp.User.Id)
 	assert.Equal(t, "John Doe", resp.User.Name)
 	assert.Equal(t, "john@example.com", resp.User.Email)
+	assert.True(t, user.CreatedAt.Equal(resp.User.CreatedAt.AsTime()))
+	assert.True(t, user.UpdatedAt.Equal(resp.User.UpdatedAt.AsTime()))
+	assert.Nil(t, resp.User.DeletedAt)
+
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	user.DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+
+	assert.Equal(t, deletedAt, user.ToProtoUserResponse().User.DeletedAt.AsTime())
 }
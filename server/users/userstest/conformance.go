@@ -0,0 +1,502 @@
+// Package userstest checks that implementations of
+// users.UserRepositoryInterface behave like the GORM repository.
+package userstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cndrsdrmn/go-grpc/server/filter"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Factory returns an empty repository. It is called once per subtest, so
+// implementations backed by shared storage must clear it first.
+type Factory func(t *testing.T) users.UserRepositoryInterface
+
+// RunRepositoryConformance runs the shared behaviour checks against the
+// repositories returned by factory. It lowers the bcrypt cost for the
+// duration of the run to keep the concurrency checks fast.
+func RunRepositoryConformance(t *testing.T, factory Factory) {
+	cost := users.PasswordCost()
+	require.NoError(t, users.SetPasswordCost(bcrypt.MinCost))
+	t.Cleanup(func() { users.SetPasswordCost(cost) })
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo users.UserRepositoryInterface)
+	}{
+		{"CreateUser", testCreateUser},
+		{"CreateUsers", testCreateUsers},
+		{"FindUser", testFindUser},
+		{"UpdateUser", testUpdateUser},
+		{"DeleteUser", testDeleteUser},
+		{"RestoreAndPurgeUser", testRestoreAndPurgeUser},
+		{"Roles", testRoles},
+		{"ListUsers", testListUsers},
+		{"AllAndStreamUsers", testAllAndStreamUsers},
+		{"ConcurrentCreates", testConcurrentCreates},
+		{"ConcurrentUpdates", testConcurrentUpdates},
+		{"CancelledContext", testCancelledContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, factory(t))
+		})
+	}
+}
+
+func create(t *testing.T, repo users.UserRepositoryInterface, name string) *users.User {
+	t.Helper()
+	user := &users.User{Name: name, Email: strings.ToLower(name) + "@example.com", Password: "secret"}
+	require.NoError(t, repo.CreateUser(t.Context(), user))
+	return user
+}
+
+func names(list []users.User) []string {
+	out := []string{}
+	for _, user := range list {
+		out = append(out, user.Name)
+	}
+	return out
+}
+
+func testCreateUser(t *testing.T, repo users.UserRepositoryInterface) {
+	user := &users.User{Name: "John", Email: "john@example.com", Password: "secret"}
+
+	require.NoError(t, repo.CreateUser(t.Context(), user))
+	assert.NotZero(t, user.ID)
+	assert.NotZero(t, user.CreatedAt)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("secret")))
+
+	found, err := repo.FindUser(t.Context(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), found.Version)
+	assert.Equal(t, user.Password, found.Password)
+
+	err = repo.CreateUser(t.Context(), &users.User{Name: "Other", Email: "john@example.com", Password: "secret"})
+	assert.ErrorIs(t, err, users.ErrEmailAlreadyExists)
+
+	err = repo.CreateUser(t.Context(), &users.User{Model: gorm.Model{ID: user.ID}, Name: "Twin", Email: "twin@example.com", Password: "secret"})
+	assert.ErrorIs(t, err, users.ErrUserIDAlreadyExists)
+	_, err = repo.FindUserByEmail(t.Context(), "twin@example.com")
+	assert.ErrorIs(t, err, users.ErrUserNotFound)
+
+	err = repo.CreateUser(t.Context(), &users.User{Model: gorm.Model{ID: user.ID}, Name: "John", Email: user.Email, Password: "secret"})
+	assert.ErrorIs(t, err, users.ErrUserIDAlreadyExists, "a taken id is reported before a taken email")
+
+	err = repo.CreateUser(t.Context(), &users.User{Name: "Long", Email: "long@example.com", Password: strings.Repeat("x", 73)})
+	assert.ErrorIs(t, err, users.ErrPasswordTooLong)
+
+	second := create(t, repo, "Jane")
+	assert.Greater(t, second.ID, user.ID)
+}
+
+func testCreateUsers(t *testing.T, repo users.UserRepositoryInterface) {
+	create(t, repo, "Charlie")
+
+	batch := []*users.User{
+		{Name: "David", Email: "david@example.com", Password: "password"},
+		{Name: "Charlie", Email: "charlie@example.com", Password: "secret"},
+		{Name: "Eve", Email: "eve@example.com", Password: strings.Repeat("x", 73)},
+		{Name: "David Again", Email: "david@example.com", Password: "password"},
+		{Name: "Frank", Email: "frank@example.com", Password: "password"},
+	}
+
+	errs := repo.CreateUsers(t.Context(), batch)
+
+	require.Len(t, errs, len(batch))
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], users.ErrEmailAlreadyExists)
+	assert.ErrorIs(t, errs[2], users.ErrPasswordTooLong)
+	assert.ErrorIs(t, errs[3], users.ErrEmailAlreadyExists)
+	assert.NoError(t, errs[4])
+
+	found, err := repo.FindUser(t.Context(), batch[4].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Frank", found.Name)
+
+	all, err := repo.AllUser(t.Context())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Charlie", "David", "Frank"}, names(all))
+}
+
+func testFindUser(t *testing.T, repo users.UserRepositoryInterface) {
+	user := create(t, repo, "John")
+
+	found, err := repo.FindUserByEmail(t.Context(), "john@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+
+	found.Name = "Changed"
+	again, err := repo.FindUser(t.Context(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "John", again.Name, "returned users must not alias stored state")
+
+	_, err = repo.FindUser(t.Context(), user.ID+100)
+	assert.ErrorIs(t, err, users.ErrUserNotFound)
+
+	_, err = repo.FindUserByEmail(t.Context(), "nobody@example.com")
+	assert.ErrorIs(t, err, users.ErrUserNotFound)
+}
+
+func testUpdateUser(t *testing.T, repo users.UserRepositoryInterface) {
+	user := create(t, repo, "John")
+	create(t, repo, "Jane")
+
+	t.Run("changes only the listed fields", func(t *testing.T) {
+		update := &users.User{Name: "ignored", Email: "john.new@example.com", Password: "new-secret"}
+		require.NoError(t, repo.UpdateUser(t.Context(), user.ID, update, []string{"email", "password"}))
+
+		assert.Equal(t, "John", update.Name)
+		assert.Equal(t, "john.new@example.com", update.Email)
+		assert.Equal(t, uint(2), update.Version)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(update.Password), []byte("new-secret")))
+
+		_, err := repo.FindUserByEmail(t.Context(), "john@example.com")
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+	})
+
+	t.Run("rejects clearing the name", func(t *testing.T) {
+		var invalid *users.ValidationError
+		assert.ErrorAs(t, repo.UpdateUser(t.Context(), user.ID, &users.User{}, []string{"name"}), &invalid)
+
+		found, err := repo.FindUser(t.Context(), user.ID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, found.Name)
+	})
+
+	t.Run("checks the version", func(t *testing.T) {
+		current, err := repo.FindUser(t.Context(), user.ID)
+		require.NoError(t, err)
+
+		err = repo.UpdateUser(t.Context(), user.ID, &users.User{Name: "Stale", Version: current.Version - 1}, []string{"name"})
+		assert.ErrorIs(t, err, users.ErrVersionConflict)
+
+		update := &users.User{Name: "Fresh", Version: current.Version}
+		assert.NoError(t, repo.UpdateUser(t.Context(), user.ID, update, []string{"name"}))
+		assert.Equal(t, current.Version+1, update.Version)
+	})
+
+	t.Run("rejects invalid updates", func(t *testing.T) {
+		err := repo.UpdateUser(t.Context(), user.ID, &users.User{Email: "jane@example.com"}, []string{"email"})
+		assert.ErrorIs(t, err, users.ErrEmailAlreadyExists)
+
+		err = repo.UpdateUser(t.Context(), user.ID+100, &users.User{Name: "Nobody"}, []string{"name"})
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+
+		err = repo.UpdateUser(t.Context(), user.ID+100, &users.User{Name: "Nobody", Version: 1}, []string{"name"})
+		assert.ErrorIs(t, err, users.ErrUserNotFound)
+
+		err = repo.UpdateUser(t.Context(), user.ID, &users.User{}, nil)
+		assert.ErrorIs(t, err, users.ErrNoFieldsToUpdate)
+
+		err = repo.UpdateUser(t.Context(), user.ID, &users.User{}, []string{"roles"})
+		assert.ErrorIs(t, err, users.ErrInvalidUpdateMask)
+	})
+}
+
+func testDeleteUser(t *testing.T, repo users.UserRepositoryInterface) {
+	user := create(t, repo, "John")
+
+	require.NoError(t, repo.DeleteUser(t.Context(), user.ID))
+
+	_, err := repo.FindUser(t.Context(), user.ID)
+	assert.ErrorIs(t, err, users.ErrUserNotFound)
+	_, err = repo.FindUserByEmail(t.Context(), user.Email)
+	assert.ErrorIs(t, err, users.ErrUserNotFound)
+
+	assert.ErrorIs(t, repo.DeleteUser(t.Context(), user.ID), users.ErrUserNotFound)
+	assert.ErrorIs(t, repo.DeleteUser(t.Context(), user.ID+100), users.ErrUserNotFound)
+	assert.ErrorIs(t, repo.UpdateUser(t.Context(), user.ID, &users.User{Name: "Ghost"}, []string{"name"}), users.ErrUserNotFound)
+
+	err = repo.CreateUser(t.Context(), &users.User{Name: "Again", Email: user.Email, Password: "secret"})
+	assert.ErrorIs(t, err, users.ErrEmailAlreadyExists, "a deleted user keeps its email reserved")
+}
+
+func testRestoreAndPurgeUser(t *testing.T, repo users.UserRepositoryInterface) {
+	user := create(t, repo, "John")
+
+	assert.ErrorIs(t, repo.RestoreUser(t.Context(), user.ID), users.ErrUserNotDeleted)
+	assert.ErrorIs(t, repo.RestoreUser(t.Context(), user.ID+100), users.ErrUserNotFound)
+
+	require.NoError(t, repo.DeleteUser(t.Context(), user.ID))
+	require.NoError(t, repo.RestoreUser(t.Context(), user.ID))
+	found, err := repo.FindUser(t.Context(), user.ID)
+	require.NoError(t, err)
+	assert.False(t, found.DeletedAt.Valid)
+
+	require.NoError(t, repo.DeleteUser(t.Context(), user.ID))
+	require.NoError(t, repo.PurgeUser(t.Context(), user.ID))
+	assert.ErrorIs(t, repo.PurgeUser(t.Context(), user.ID), users.ErrUserNotFound)
+	assert.ErrorIs(t, repo.RestoreUser(t.Context(), user.ID), users.ErrUserNotFound)
+
+	listed, err := repo.ListUsers(t.Context(), users.ListQuery{Limit: 10, ShowDeleted: true})
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+
+	assert.NoError(t, repo.CreateUser(t.Context(), &users.User{Name: "Again", Email: user.Email, Password: "secret"}), "purging frees the email")
+}
+
+func testRoles(t *testing.T, repo users.UserRepositoryInterface) {
+	user := create(t, repo, "John")
+
+	hasAdmin := func(t *testing.T) bool {
+		t.Helper()
+		ok, err := repo.HasRole(t.Context(), users.RoleAdmin)
+		require.NoError(t, err)
+		return ok
+	}
+	assert.False(t, hasAdmin(t))
+
+	require.NoError(t, repo.GrantRole(t.Context(), user.ID, users.RoleManager))
+	require.NoError(t, repo.GrantRole(t.Context(), user.ID, users.RoleAdmin))
+	assert.True(t, hasAdmin(t))
+	require.NoError(t, repo.GrantRole(t.Context(), user.ID, users.RoleAdmin), "granting twice is a no-op")
+
+	roles, err := repo.FindRoles(t.Context(), user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{users.RoleAdmin, users.RoleManager}, roles)
+
+	found, err := repo.FindUser(t.Context(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{users.RoleAdmin, users.RoleManager}, found.RoleNames())
+
+	require.NoError(t, repo.RevokeRole(t.Context(), user.ID, users.RoleManager))
+	require.NoError(t, repo.RevokeRole(t.Context(), user.ID, users.RoleManager), "revoking a missing role is a no-op")
+	roles, _ = repo.FindRoles(t.Context(), user.ID)
+	assert.Equal(t, []string{users.RoleAdmin}, roles)
+
+	assert.ErrorIs(t, repo.GrantRole(t.Context(), user.ID, "owner"), users.ErrInvalidRole)
+	assert.ErrorIs(t, repo.RevokeRole(t.Context(), user.ID, "owner"), users.ErrInvalidRole)
+	assert.ErrorIs(t, repo.GrantRole(t.Context(), user.ID+100, users.RoleAdmin), users.ErrUserNotFound)
+	assert.ErrorIs(t, repo.RevokeRole(t.Context(), user.ID+100, users.RoleAdmin), users.ErrUserNotFound)
+
+	require.NoError(t, repo.DeleteUser(t.Context(), user.ID))
+	roles, err = repo.FindRoles(t.Context(), user.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, roles, "a deleted user holds no roles")
+	assert.False(t, hasAdmin(t), "a deleted admin does not count")
+	assert.ErrorIs(t, repo.GrantRole(t.Context(), user.ID, users.RoleAdmin), users.ErrUserNotFound)
+
+	require.NoError(t, repo.RestoreUser(t.Context(), user.ID))
+	roles, _ = repo.FindRoles(t.Context(), user.ID)
+	assert.Equal(t, []string{users.RoleAdmin}, roles, "restoring brings the roles back")
+}
+
+func testListUsers(t *testing.T, repo users.UserRepositoryInterface) {
+	for _, name := range []string{"Charlie", "alice", "Bob", "Alicia", "Dave"} {
+		create(t, repo, name)
+	}
+
+	list := func(t *testing.T, query users.ListQuery) []string {
+		t.Helper()
+		found, err := repo.ListUsers(t.Context(), query)
+		require.NoError(t, err)
+		return names(found)
+	}
+
+	t.Run("orders by id by default", func(t *testing.T) {
+		assert.Equal(t, []string{"Charlie", "alice", "Bob"}, list(t, users.ListQuery{Limit: 3}))
+	})
+
+	t.Run("filters, orders and continues after a cursor", func(t *testing.T) {
+		where, err := filter.Parse(`name = "ali*" OR name = "Bob"`)
+		require.NoError(t, err)
+		order, err := users.ParseOrderBy("name desc")
+		require.NoError(t, err)
+
+		first, err := repo.ListUsers(t.Context(), users.ListQuery{Filter: where, OrderBy: order, Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"alice", "Bob"}, names(first))
+
+		rest := list(t, users.ListQuery{Filter: where, OrderBy: order, After: &first[1], Limit: 2})
+		assert.Equal(t, []string{"Alicia"}, rest)
+	})
+
+	t.Run("compares strings case-sensitively and wildcards case-insensitively", func(t *testing.T) {
+		where, _ := filter.Parse(`name < "a" AND NOT name = "*e"`)
+		assert.Equal(t, []string{"Bob", "Alicia"}, list(t, users.ListQuery{Filter: where, Limit: 10}))
+	})
+
+	t.Run("hides deleted users unless asked", func(t *testing.T) {
+		dave, err := repo.FindUserByEmail(t.Context(), "dave@example.com")
+		require.NoError(t, err)
+		require.NoError(t, repo.DeleteUser(t.Context(), dave.ID))
+
+		where, _ := filter.Parse(`email = "dave@example.com"`)
+		assert.Empty(t, list(t, users.ListQuery{Filter: where, Limit: 10}))
+		assert.Equal(t, []string{"Dave"}, list(t, users.ListQuery{Filter: where, Limit: 10, ShowDeleted: true}))
+	})
+
+	t.Run("rejects unknown fields", func(t *testing.T) {
+		where, _ := filter.Parse(`password = "secret"`)
+		_, err := repo.ListUsers(t.Context(), users.ListQuery{Filter: where, Limit: 10})
+		assert.ErrorIs(t, err, users.ErrInvalidFilter)
+	})
+}
+
+func testAllAndStreamUsers(t *testing.T, repo users.UserRepositoryInterface) {
+	for i := range 5 {
+		create(t, repo, fmt.Sprintf("User%d", i))
+	}
+	deleted := create(t, repo, "Deleted")
+	require.NoError(t, repo.DeleteUser(t.Context(), deleted.ID))
+
+	all, err := repo.AllUser(t.Context())
+	assert.NoError(t, err)
+	assert.Len(t, all, 5)
+
+	var batches []int
+	err = repo.StreamUsers(t.Context(), 2, func(batch []users.User) error {
+		batches = append(batches, len(batch))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 2, 1}, batches)
+
+	stop := errors.New("stop")
+	calls := 0
+	err = repo.StreamUsers(t.Context(), 2, func([]users.User) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func testConcurrentCreates(t *testing.T, repo users.UserRepositoryInterface) {
+	const writers = 20
+
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = repo.CreateUser(t.Context(), &users.User{Name: "User", Email: fmt.Sprintf("user%d@example.com", i), Password: "secret"})
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	all, err := repo.AllUser(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, all, writers)
+
+	ids := map[uint]bool{}
+	for _, user := range all {
+		ids[user.ID] = true
+	}
+	assert.Len(t, ids, writers, "every user gets its own id")
+
+	succeeded := 0
+	var mu sync.Mutex
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.CreateUser(t.Context(), &users.User{Name: "Same", Email: "same@example.com", Password: "secret"})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, users.ErrEmailAlreadyExists)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, succeeded, "only one writer may claim an email")
+}
+
+func testConcurrentUpdates(t *testing.T, repo users.UserRepositoryInterface) {
+	const writers = 10
+	user := create(t, repo, "John")
+
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			update := &users.User{Name: fmt.Sprintf("Writer%d", i), Version: user.Version}
+			errs[i] = repo.UpdateUser(t.Context(), user.ID, update, []string{"name"})
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, users.ErrVersionConflict)
+	}
+	assert.Equal(t, 1, succeeded, "only one writer may update a given version")
+
+	found, err := repo.FindUser(t.Context(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.Version+1, found.Version)
+}
+
+func testCancelledContext(t *testing.T, repo users.UserRepositoryInterface) {
+	user := create(t, repo, "John")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	calls := map[string]func() error{
+		"AllUser": func() error { _, err := repo.AllUser(ctx); return err },
+		"ListUsers": func() error {
+			_, err := repo.ListUsers(ctx, users.ListQuery{Limit: 10})
+			return err
+		},
+		"StreamUsers": func() error {
+			return repo.StreamUsers(ctx, 10, func([]users.User) error { return nil })
+		},
+		"CreateUser": func() error {
+			return repo.CreateUser(ctx, &users.User{Name: "Jane", Email: "jane@example.com", Password: "secret"})
+		},
+		"CreateUsers": func() error {
+			return errors.Join(repo.CreateUsers(ctx, []*users.User{{Name: "Jack", Email: "jack@example.com", Password: "secret"}})...)
+		},
+		"FindUser":        func() error { _, err := repo.FindUser(ctx, user.ID); return err },
+		"FindUserByEmail": func() error { _, err := repo.FindUserByEmail(ctx, user.Email); return err },
+		"UpdateUser": func() error {
+			return repo.UpdateUser(ctx, user.ID, &users.User{Name: "Johnny"}, []string{"name"})
+		},
+		"DeleteUser":  func() error { return repo.DeleteUser(ctx, user.ID) },
+		"RestoreUser": func() error { return repo.RestoreUser(ctx, user.ID) },
+		"PurgeUser":   func() error { return repo.PurgeUser(ctx, user.ID) },
+		"FindRoles":   func() error { _, err := repo.FindRoles(ctx, user.ID); return err },
+		"HasRole":     func() error { _, err := repo.HasRole(ctx, users.RoleAdmin); return err },
+		"GrantRole":   func() error { return repo.GrantRole(ctx, user.ID, users.RoleAdmin) },
+		"RevokeRole":  func() error { return repo.RevokeRole(ctx, user.ID, users.RoleAdmin) },
+	}
+	for name, call := range calls {
+		assert.ErrorIs(t, call(), context.Canceled, name)
+	}
+
+	all, err := repo.AllUser(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"John"}, names(all), "cancelled calls must not change anything")
+	assert.Empty(t, all[0].RoleNames())
+
+	expired, cancel := context.WithTimeout(t.Context(), -1)
+	defer cancel()
+	_, err = repo.FindUser(expired, user.ID)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,183 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	pb "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Field+": "+v.Description)
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) GRPCStatus() *status.Status {
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+
+	return withDetails(
+		status.New(codes.InvalidArgument, e.Error()),
+		&errdetails.ErrorInfo{Reason: ReasonInvalidArgument, Domain: ErrorDomain},
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+}
+
+func Validate(msg proto.Message) error {
+	m := msg.ProtoReflect()
+	fields := m.Descriptor().Fields()
+
+	var violations []FieldViolation
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+
+		rules, ok := proto.GetExtension(fd.Options(), pb.E_Rules).(*pb.FieldRules)
+		if !ok || rules == nil {
+			continue
+		}
+
+		if fd.HasPresence() && !m.Has(fd) {
+			continue
+		}
+
+		if desc := checkField(fd, m.Get(fd), rules); desc != "" {
+			violations = append(violations, FieldViolation{Field: string(fd.Name()), Description: desc})
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+func checkField(fd protoreflect.FieldDescriptor, value protoreflect.Value, rules *pb.FieldRules) string {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return checkString(value.String(), rules)
+	case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Sint32Kind, protoreflect.Sint64Kind,
+		protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
+		return checkInt(value.Int(), rules)
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind, protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		return checkInt(int64(min(value.Uint(), math.MaxInt64)), rules)
+	case protoreflect.EnumKind:
+		if rules.GetDefinedOnly() && fd.Enum().Values().ByNumber(value.Enum()) == nil {
+			return "must be a defined value"
+		}
+		return checkInt(int64(value.Enum()), rules)
+	}
+	return ""
+}
+
+func checkString(value string, rules *pb.FieldRules) string {
+	if value == "" && rules.GetIgnoreEmpty() {
+		return ""
+	}
+
+	if rules.GetNotBlank() && strings.TrimSpace(value) == "" {
+		return "must not be empty"
+	}
+
+	if rules.MinLen != nil && uint32(utf8.RuneCountInString(value)) < rules.GetMinLen() {
+		if rules.GetMinLen() == 1 {
+			return "must not be empty"
+		}
+		return fmt.Sprintf("must be at least %d characters", rules.GetMinLen())
+	}
+
+	if rules.MaxLen != nil && uint32(utf8.RuneCountInString(value)) > rules.GetMaxLen() {
+		return fmt.Sprintf("must be at most %d characters", rules.GetMaxLen())
+	}
+
+	if rules.MaxBytes != nil && uint32(len(value)) > rules.GetMaxBytes() {
+		return fmt.Sprintf("must be at most %d bytes", rules.GetMaxBytes())
+	}
+
+	if rules.GetEmail() {
+		if addr, err := mail.ParseAddress(value); err != nil || addr.Address != value {
+			return "must be a valid email address"
+		}
+	}
+
+	return ""
+}
+
+func checkInt(value int64, rules *pb.FieldRules) string {
+	if rules.Gt != nil && value <= rules.GetGt() {
+		return fmt.Sprintf("must be greater than %d", rules.GetGt())
+	}
+
+	if rules.Gte != nil && value < rules.GetGte() {
+		return fmt.Sprintf("must be greater than or equal to %d", rules.GetGte())
+	}
+
+	if rules.Lte != nil && value > rules.GetLte() {
+		return fmt.Sprintf("must be less than or equal to %d", rules.GetLte())
+	}
+
+	return ""
+}
+
+func UnaryValidationInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if msg, ok := req.(proto.Message); ok {
+			if err := Validate(msg); err != nil {
+				return nil, toStatusError(err)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamValidationInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if info.IsClientStream {
+			return handler(srv, ss)
+		}
+		return handler(srv, &validatingServerStream{ServerStream: ss})
+	}
+}
+
+type validatingServerStream struct {
+	grpc.ServerStream
+}
+
+func (s *validatingServerStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if msg, ok := m.(proto.Message); ok {
+		if err := Validate(msg); err != nil {
+			return toStatusError(err)
+		}
+	}
+	return nil
+}
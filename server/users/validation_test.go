@@ -0,0 +1,150 @@
+package users_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	protos "github.com/cndrsdrmn/go-grpc/protos/gen"
+	"github.com/cndrsdrmn/go-grpc/server/users"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func fieldViolations(t *testing.T, err error) map[string]string {
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+
+	violations := make(map[string]string)
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			assert.Equal(t, users.ReasonInvalidArgument, d.Reason)
+			assert.Equal(t, users.ErrorDomain, d.Domain)
+		case *errdetails.BadRequest:
+			for _, v := range d.FieldViolations {
+				violations[v.Field] = v.Description
+			}
+		}
+	}
+	return violations
+}
+
+func TestValidateCreateUserRequest(t *testing.T) {
+	t.Run("reports every invalid field at once", func(t *testing.T) {
+		err := users.Validate(&protos.CreateUserRequest{
+			Name:     " ",
+			Email:    "not-an-email",
+			Password: strings.Repeat("x", 73),
+		})
+
+		violations := fieldViolations(t, err)
+		assert.Equal(t, map[string]string{
+			"name":     "must not be empty",
+			"email":    "must be a valid email address",
+			"password": "must be at most 72 bytes",
+		}, violations)
+	})
+
+	t.Run("requires email and password", func(t *testing.T) {
+		err := users.Validate(&protos.CreateUserRequest{Name: "John Doe"})
+
+		violations := fieldViolations(t, err)
+		assert.Equal(t, map[string]string{
+			"email":    "must not be empty",
+			"password": "must not be empty",
+		}, violations)
+	})
+
+	t.Run("rejects a display name in the email", func(t *testing.T) {
+		err := users.Validate(&protos.CreateUserRequest{
+			Name:     "John Doe",
+			Email:    "John <john@example.com>",
+			Password: "secret",
+		})
+
+		violations := fieldViolations(t, err)
+		assert.Equal(t, map[string]string{"email": "must be a valid email address"}, violations)
+	})
+
+	t.Run("accepts a valid request", func(t *testing.T) {
+		err := users.Validate(&protos.CreateUserRequest{
+			Name:     "John Doe",
+			Email:    "john@example.com",
+			Password: "secret",
+		})
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestValidateUpdateUserRequest(t *testing.T) {
+	t.Run("validates only the provided fields", func(t *testing.T) {
+		err := users.Validate(&protos.UpdateUserRequest{Id: 1})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("reports every invalid field at once", func(t *testing.T) {
+		email := "invalid"
+		password := ""
+		err := users.Validate(&protos.UpdateUserRequest{
+			Name:     strings.Repeat("x", 101),
+			Email:    &email,
+			Password: &password,
+		})
+
+		violations := fieldViolations(t, err)
+		assert.Equal(t, map[string]string{
+			"id":       "must be greater than 0",
+			"name":     "must be at most 100 characters",
+			"email":    "must be a valid email address",
+			"password": "must not be empty",
+		}, violations)
+	})
+}
+
+func TestValidateIDRequests(t *testing.T) {
+	assert.Equal(t, "must be greater than 0", fieldViolations(t, users.Validate(&protos.GetUserRequest{}))["id"])
+	assert.Equal(t, "must be greater than 0", fieldViolations(t, users.Validate(&protos.DeleteUserRequest{}))["id"])
+	assert.NoError(t, users.Validate(&protos.GetUserRequest{Id: 1}))
+}
+
+func TestValidateRoleRequest(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"user_id": "must be greater than 0",
+		"role":    "must be greater than 0",
+	}, fieldViolations(t, users.Validate(&protos.RoleRequest{})))
+	assert.Equal(t, "must be a defined value", fieldViolations(t, users.Validate(&protos.RoleRequest{UserId: 1, Role: 42}))["role"])
+	assert.NoError(t, users.Validate(&protos.RoleRequest{UserId: 1, Role: protos.Role_ROLE_ADMIN}))
+}
+
+func TestUnaryValidationInterceptor(t *testing.T) {
+	interceptor := users.UnaryValidationInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/protos.UserService/GetUser"}
+
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return &protos.UserResponse{}, nil
+	}
+
+	t.Run("rejects an invalid request before the handler", func(t *testing.T) {
+		called = false
+		_, err := interceptor(context.Background(), &protos.GetUserRequest{}, info, handler)
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.False(t, called)
+	})
+
+	t.Run("passes a valid request to the handler", func(t *testing.T) {
+		called = false
+		_, err := interceptor(context.Background(), &protos.GetUserRequest{Id: 1}, info, handler)
+
+		assert.NoError(t, err)
+		assert.True(t, called)
+	})
+}